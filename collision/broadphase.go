@@ -0,0 +1,70 @@
+package collision
+
+import (
+	"engine/entities"
+	"sort"
+)
+
+/*
+The broadphase is a cheap first pass over the bodies of the world. It only
+looks at bounding boxes and returns the pairs that might be touching, the
+expensive shape specific checks (narrowphase) are only run on those pairs.
+*/
+type Broadphase interface {
+	// Syncs the broadphase with the current bounding boxes of the bodies.
+	Update(bodies []*entities.Body)
+	// Candidate pairs whose bounding boxes overlap since the last Update.
+	Pairs() []Pair
+	// Calls callback for every body whose box overlaps aabb. Returning false stops the search.
+	Query(aabb entities.AABB, callback func(body *entities.Body) bool)
+}
+
+/*
+BodyA is always the body with the lowest ID (see Body.ID), the same two
+bodies come in the same order even after they were removed and added back
+to the world, so their collisions keep the same PairKey.
+*/
+type Pair struct {
+	BodyA *entities.Body
+	BodyB *entities.Body
+}
+
+func newPair(bodyA *entities.Body, bodyB *entities.Body) Pair {
+	if bodyA.ID() > bodyB.ID() {
+		bodyA, bodyB = bodyB, bodyA
+	}
+	return Pair{BodyA: bodyA, BodyB: bodyB}
+}
+
+// Pairs are sorted by the index of the body in the world so the output is deterministic.
+type indexPair struct {
+	a int
+	b int
+}
+
+func newIndexPair(a int, b int) indexPair {
+	if a > b {
+		a, b = b, a
+	}
+	return indexPair{a: a, b: b}
+}
+
+func sortedPairs(found map[indexPair]bool, bodies []*entities.Body) []Pair {
+	keys := make([]indexPair, 0, len(found))
+	for key := range found {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].a == keys[j].a {
+			return keys[i].b < keys[j].b
+		}
+		return keys[i].a < keys[j].a
+	})
+
+	pairs := make([]Pair, len(keys))
+	for i, key := range keys {
+		pairs[i] = newPair(bodies[key.a], bodies[key.b])
+	}
+	return pairs
+}
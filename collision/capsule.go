@@ -0,0 +1,344 @@
+package collision
+
+import (
+	"engine/constants"
+	"engine/entities"
+	"engine/vector"
+	"math"
+)
+
+/*
+A capsule is its segment grown by the radius, so the capsule routines work
+with the segment and add the radius at the end: two shapes touch when the
+distance between the segment and the other shape is under the radius.
+*/
+func calculateCapsuleCircleCollision(capsule *entities.Capsule, circle *entities.Circle) *Collision {
+	closest := closestPointOnSegment(circle.Center, capsule.A, capsule.B)
+	return roundCollision(closest, capsule.Radius, circle.Center, float64(circle.Radius), capsule.B.Subtract(capsule.A))
+}
+
+/*
+Segments that are almost parallel rest on each other along a line (two
+capsules lying on top of each other), B's segment is clipped against the
+ends of A's and every point that is close enough is a contact. Otherwise
+they only touch at the closest points of both segments.
+*/
+func calculateCapsuleCapsuleCollision(capsuleA *entities.Capsule, capsuleB *entities.Capsule) *Collision {
+	segmentA := capsuleA.B.Subtract(capsuleA.A)
+	segmentB := capsuleB.B.Subtract(capsuleB.A)
+
+	if collision := parallelCapsules(capsuleA, capsuleB, segmentA, segmentB); collision != nil {
+		return collision
+	}
+
+	pointA, pointB := closestPointsBetweenSegments(capsuleA.A, capsuleA.B, capsuleB.A, capsuleB.B)
+	return roundCollision(pointA, capsuleA.Radius, pointB, capsuleB.Radius, segmentA)
+}
+
+func parallelCapsules(capsuleA *entities.Capsule, capsuleB *entities.Capsule, segmentA vector.Vec2, segmentB vector.Vec2) *Collision {
+	const parallelTolerance = 0.05 // sin of the angle between the segments, ~3 degrees
+
+	lengthA := segmentA.Magnitude()
+	lengthB := segmentB.Magnitude()
+	if lengthA == 0 || lengthB == 0 {
+		return nil
+	}
+
+	tangent := segmentA.Multiply(1 / lengthA)
+	if math.Abs(tangent.Cross(segmentB))/lengthB > parallelTolerance {
+		return nil
+	}
+
+	// The normal of A's segment that faces B
+	normal := segmentA.Normal()
+	d := capsuleB.A.Subtract(capsuleA.A)
+	if d.Dot(normal) < 0 {
+		normal = normal.Multiply(-1)
+	}
+
+	points := []clipVertex{
+		{point: capsuleB.A, vertex: 0},
+		{point: capsuleB.B, vertex: 1},
+	}
+	points = clipSegmentToLine(points, tangent.Multiply(-1), -tangent.Dot(capsuleA.A), -1)
+	if len(points) < 2 {
+		return nil
+	}
+	points = clipSegmentToLine(points, tangent, tangent.Dot(capsuleA.B), -2)
+	if len(points) < 2 {
+		return nil
+	}
+
+	radius := capsuleA.Radius + capsuleB.Radius
+	var contacts []Contact
+	for _, clipped := range points {
+		d := clipped.point.Subtract(capsuleA.A)
+		separation := d.Dot(normal)
+		if separation > radius+constants.CONTACT_MARGIN {
+			continue
+		}
+
+		start := clipped.point.Subtract(normal.Multiply(capsuleB.Radius))
+		depth := radius - separation
+		contacts = append(contacts, Contact{
+			Start: start,
+			End:   start.Add(normal.Multiply(depth)),
+			Depth: depth,
+			ID:    FeatureID{Vertex: clipped.vertex},
+		})
+	}
+
+	if len(contacts) == 0 {
+		return nil
+	}
+	return &Collision{Normal: normal, Contacts: contacts}
+}
+
+/*
+The polygon is tested against the segment of the capsule like against
+another polygon (see calculatePolygonPolygonCollision): the axes are the
+normals of the edges and the normal of the segment. The edge of the polygon
+the segment is the least inside of is the reference:
+
+  - The segment crosses the polygon, or the closest point of the polygon is
+    on that edge: the segment is clipped against the sides of the edge,
+    lying capsules get two contacts.
+  - The closest point of the polygon is a vertex: one contact between the
+    vertex and the segment. The segment can lie across that vertex without
+    being outside of any edge, only its own normal tells them apart.
+*/
+func calculatePolygonCapsuleCollision(polygon *entities.Polygon, capsule *entities.Capsule) *Collision {
+	separation := math.Inf(-1)
+	edgeIdx := -1
+	for idx, vertex := range polygon.WorldVertices {
+		edge := polygon.EdgeAt(idx)
+		normal := edge.Normal()
+		da := capsule.A.Subtract(vertex)
+		db := capsule.B.Subtract(vertex)
+		edgeSeparation := math.Min(da.Dot(normal), db.Dot(normal))
+		if edgeSeparation > separation {
+			separation = edgeSeparation
+			edgeIdx = idx
+		}
+	}
+
+	segmentSeparation := segmentAxisSeparation(polygon, capsule.A, capsule.B)
+	if math.Max(separation, segmentSeparation) > capsule.Radius+constants.CONTACT_MARGIN {
+		return nil
+	}
+
+	edge := polygon.EdgeAt(edgeIdx)
+	edgeNormal := edge.Normal()
+	if separation > 0 || segmentSeparation > 0 {
+		pointPolygon, pointSegment := closestPolygonSegment(polygon, capsule.A, capsule.B)
+		d := pointSegment.Subtract(pointPolygon)
+		distance := d.Magnitude()
+		if distance > capsule.Radius+constants.CONTACT_MARGIN {
+			return nil
+		}
+
+		/*
+			Only a capsule lying on the edge gets two contacts. Anything else
+			touches at the closest points, clipping a tilted segment against
+			the sides of the edge can cut away the point that touches.
+		*/
+		const faceTolerance = 0.999
+		const parallelTolerance = 0.05 // sin of the angle between the segment and the edge
+		segment := capsule.B.Subtract(capsule.A)
+		length := segment.Magnitude()
+		lying := length > 0 && math.Abs(segment.Dot(edgeNormal))/length < parallelTolerance
+		if distance > 0 && (!lying || d.Dot(edgeNormal)/distance < faceTolerance) {
+			return roundCollision(pointPolygon, 0, pointSegment, capsule.Radius, edge)
+		}
+	}
+
+	return clipSegmentToEdge(polygon, edgeIdx, capsule.A, capsule.B, capsule.Radius)
+}
+
+/*
+How far the polygon is from the line of the segment a-b, along the normal of
+the segment. Negative if the line goes through the polygon, and -Inf for a
+segment without length (it has no normal, the edges are enough for a point).
+*/
+func segmentAxisSeparation(polygon *entities.Polygon, a vector.Vec2, b vector.Vec2) float64 {
+	segment := b.Subtract(a)
+	if segment.Magnitude() == 0 {
+		return math.Inf(-1)
+	}
+
+	normal := segment.Normal()
+	lowest := math.Inf(1)
+	highest := math.Inf(-1)
+	for _, vertex := range polygon.WorldVertices {
+		d := vertex.Subtract(a)
+		projection := d.Dot(normal)
+		lowest = math.Min(lowest, projection)
+		highest = math.Max(highest, projection)
+	}
+
+	// Polygon on the side the normal points to, or on the other one
+	return math.Max(lowest, -highest)
+}
+
+/*
+Contacts between the edge of the polygon at edgeIdx and the segment a-b
+grown by radius, the segment is clipped against the side planes of the edge
+(see clipContacts).
+*/
+func clipSegmentToEdge(polygon *entities.Polygon, edgeIdx int, a vector.Vec2, b vector.Vec2, radius float64) *Collision {
+	edge := polygon.EdgeAt(edgeIdx)
+	normal := edge.Normal()
+	tangent := edge.Unit()
+	v1 := polygon.WorldVertices[edgeIdx]
+	v2 := polygon.WorldVertices[(edgeIdx+1)%len(polygon.WorldVertices)]
+
+	points := []clipVertex{{point: a, vertex: 0}, {point: b, vertex: 1}}
+	if clipped := clipSegmentToLine(points, tangent.Multiply(-1), -tangent.Dot(v1), -1); len(clipped) == 2 {
+		points = clipped
+	}
+	if clipped := clipSegmentToLine(points, tangent, tangent.Dot(v2), -2); len(clipped) == 2 {
+		points = clipped
+	}
+
+	var contacts []Contact
+	for _, clipped := range points {
+		d := clipped.point.Subtract(v1)
+		separation := d.Dot(normal)
+		if separation > radius+constants.CONTACT_MARGIN {
+			continue
+		}
+
+		// Deepest point of the capsule and its projection on the edge
+		start := clipped.point.Subtract(normal.Multiply(radius))
+		depth := radius - separation
+		contacts = append(contacts, Contact{
+			Start: start,
+			End:   start.Add(normal.Multiply(depth)),
+			Depth: depth,
+			ID:    FeatureID{ReferenceEdge: edgeIdx, Vertex: clipped.vertex},
+		})
+	}
+
+	if len(contacts) == 0 {
+		return nil
+	}
+	return &Collision{Normal: normal, Contacts: contacts}
+}
+
+/*
+Collision between two round things, pointA grown by radiusA and pointB grown
+by radiusB (0 for a vertex). Same as two circles, fallback is the direction
+the normal is built from when both points are on top of each other.
+*/
+func roundCollision(pointA vector.Vec2, radiusA float64, pointB vector.Vec2, radiusB float64, fallback vector.Vec2) *Collision {
+	d := pointB.Subtract(pointA)
+	distance := d.Magnitude()
+	if distance > radiusA+radiusB+constants.CONTACT_MARGIN {
+		return nil
+	}
+
+	normal := fallback.Normal()
+	if distance > 0 {
+		normal = d.Multiply(1 / distance)
+	}
+
+	start := pointB.Subtract(normal.Multiply(radiusB))
+	depth := radiusA + radiusB - distance
+	return &Collision{
+		Normal:   normal,
+		Contacts: []Contact{{Start: start, End: start.Add(normal.Multiply(depth)), Depth: depth}},
+	}
+}
+
+// Point of the segment a-b closest to point.
+func closestPointOnSegment(point vector.Vec2, a vector.Vec2, b vector.Vec2) vector.Vec2 {
+	ab := b.Subtract(a)
+	lengthSquared := ab.Dot(ab)
+	if lengthSquared == 0 {
+		return a
+	}
+
+	ap := point.Subtract(a)
+	t := math.Max(0, math.Min(ap.Dot(ab)/lengthSquared, 1))
+	return a.Add(ab.Multiply(t))
+}
+
+/*
+Closest points of the segments a1-b1 and a2-b2. Points of the segments are
+
+	a1 + (b1 - a1) * s    a2 + (b2 - a2) * t
+
+with s and t between 0 and 1. The closest pair of points of the two lines
+is clamped to the segments, clamping one changes where the other one is, so
+it is recomputed after (Real-Time Collision Detection, 5.1.9).
+*/
+func closestPointsBetweenSegments(a1 vector.Vec2, b1 vector.Vec2, a2 vector.Vec2, b2 vector.Vec2) (vector.Vec2, vector.Vec2) {
+	d1 := b1.Subtract(a1)
+	d2 := b2.Subtract(a2)
+	r := a1.Subtract(a2)
+	a := d1.Dot(d1)
+	e := d2.Dot(d2)
+	f := d2.Dot(r)
+
+	clamp := func(x float64) float64 {
+		return math.Max(0, math.Min(x, 1))
+	}
+
+	var s, t float64
+	switch {
+	case a == 0 && e == 0:
+		s, t = 0, 0
+	case a == 0:
+		s, t = 0, clamp(f/e)
+	case e == 0:
+		s, t = clamp(-d1.Dot(r)/a), 0
+	default:
+		b := d1.Dot(d2)
+		c := d1.Dot(r)
+		denominator := a*e - b*b
+
+		// Parallel segments, any s works
+		if denominator != 0 {
+			s = clamp((b*f - c*e) / denominator)
+		}
+
+		t = (b*s + f) / e
+		if t < 0 {
+			t = 0
+			s = clamp(-c / a)
+		} else if t > 1 {
+			t = 1
+			s = clamp((b - c) / a)
+		}
+	}
+
+	return a1.Add(d1.Multiply(s)), a2.Add(d2.Multiply(t))
+}
+
+/*
+Closest points of a polygon and a segment that doesn't cross it, they are
+either a vertex of the polygon and a point of the segment or an end of the
+segment and a point of an edge.
+*/
+func closestPolygonSegment(polygon *entities.Polygon, a vector.Vec2, b vector.Vec2) (vector.Vec2, vector.Vec2) {
+	best := math.Inf(1)
+	var pointPolygon, pointSegment vector.Vec2
+
+	try := func(onPolygon vector.Vec2, onSegment vector.Vec2) {
+		d := onSegment.Subtract(onPolygon)
+		if distance := d.Dot(d); distance < best {
+			best = distance
+			pointPolygon = onPolygon
+			pointSegment = onSegment
+		}
+	}
+
+	for idx, vertex := range polygon.WorldVertices {
+		next := polygon.WorldVertices[(idx+1)%len(polygon.WorldVertices)]
+		try(vertex, closestPointOnSegment(vertex, a, b))
+		try(closestPointOnSegment(a, vertex, next), a)
+		try(closestPointOnSegment(b, vertex, next), b)
+	}
+
+	return pointPolygon, pointSegment
+}
@@ -0,0 +1,104 @@
+package collision
+
+import (
+	"engine/constants"
+	"engine/entities"
+	"engine/renderer"
+	"engine/vector"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func boxBody(width float64, height float64, position vector.Vec2, rotation float64) *entities.Body {
+	box := entities.NewBoxBody(renderer.WHITE, width, height, 1, position, rotation, entities.BODY_STATIC)
+	box.Shape.UpdateVertices(position, rotation)
+	return &box
+}
+
+func capsuleBody(a vector.Vec2, b vector.Vec2, radius float64) *entities.Body {
+	capsule := &entities.Capsule{Radius: radius, LocalA: a, LocalB: b, A: a, B: b}
+	return &entities.Body{Shape: capsule, Type: entities.BODY_DYNAMIC}
+}
+
+/*
+Checks Detect against GJK: no collision past CONTACT_MARGIN, the distance as
+a negative depth while they are close, and the EPA depth once they overlap.
+When the segment of the capsule crosses the polygon the contacts come from
+clipping against an edge, their depth is along that edge so it can only be
+deeper than the EPA one.
+*/
+func checkPolygonCapsule(t *testing.T, name string, box *entities.Body, capsule *entities.Body) {
+	t.Helper()
+
+	distance, overlap := Distance(box.Shape, capsule.Shape)
+	want := -distance
+	if overlap {
+		want = calculateGJKCollision(box.Shape, capsule.Shape).Contacts[0].Depth
+	}
+
+	shape := capsule.Shape.(*entities.Capsule)
+	segment := &entities.Capsule{A: shape.A, B: shape.B}
+	_, crossing := Distance(box.Shape, segment)
+
+	depth := math.Inf(-1)
+	for _, collision := range Detect(box, capsule) {
+		for _, contact := range collision.Contacts {
+			depth = math.Max(depth, contact.Depth)
+		}
+	}
+
+	const tolerance = 0.05 // pix, EPA stops at GJK_TOLERANCE
+	switch {
+	case !overlap && distance > constants.CONTACT_MARGIN+tolerance:
+		if !math.IsInf(depth, -1) {
+			t.Errorf("%s: %.3f pix apart, got a collision with depth %.3f", name, distance, depth)
+		}
+	case !overlap && distance > constants.CONTACT_MARGIN-tolerance:
+		// Right on the margin, both answers are fine
+	case crossing:
+		if depth < want-tolerance {
+			t.Errorf("%s: depth %.3f, want at least %.3f", name, depth, want)
+		}
+	default:
+		if math.Abs(depth-want) > tolerance {
+			t.Errorf("%s: depth %.3f, want %.3f", name, depth, want)
+		}
+	}
+}
+
+func TestPolygonCapsuleCollision(t *testing.T) {
+	cases := []struct {
+		name    string
+		box     *entities.Body
+		capsule *entities.Body
+	}{
+		{"diagonal over the corner", boxBody(2, 2, vector.Vec2{}, 0), capsuleBody(vector.Vec2{X: 0, Y: 3}, vector.Vec2{X: 3, Y: 0}, 1)},
+		{"diagonal close to the corner", boxBody(2, 2, vector.Vec2{}, 0), capsuleBody(vector.Vec2{X: 0, Y: 3.5}, vector.Vec2{X: 3.5, Y: 0}, 1)},
+		{"diagonal far from the corner", boxBody(2, 2, vector.Vec2{}, 0), capsuleBody(vector.Vec2{X: 0, Y: 5}, vector.Vec2{X: 5, Y: 0}, 1)},
+		{"lying on the top edge", boxBody(50, 50, vector.Vec2{}, 0), capsuleBody(vector.Vec2{X: -10, Y: -34.5}, vector.Vec2{X: 10, Y: -34.5}, 10)},
+		{"standing on the top edge", boxBody(50, 50, vector.Vec2{}, 0), capsuleBody(vector.Vec2{X: 5, Y: -60}, vector.Vec2{X: 5, Y: -34.5}, 10)},
+		{"end over the corner", boxBody(50, 50, vector.Vec2{}, 0), capsuleBody(vector.Vec2{X: 32, Y: -32}, vector.Vec2{X: 60, Y: -60}, 10)},
+		{"crossing the box", boxBody(50, 50, vector.Vec2{}, 0), capsuleBody(vector.Vec2{X: -40, Y: -20}, vector.Vec2{X: 40, Y: -20}, 10)},
+		{"rotated box under the middle", boxBody(50, 50, vector.Vec2{}, math.Pi/4), capsuleBody(vector.Vec2{X: -30, Y: -43}, vector.Vec2{X: 30, Y: -43}, 10)},
+	}
+
+	for _, c := range cases {
+		checkPolygonCapsule(t, c.name, c.box, c.capsule)
+	}
+}
+
+func TestPolygonCapsuleCollisionRandom(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	point := func(extent float64) vector.Vec2 {
+		return vector.Vec2{X: (random.Float64()*2 - 1) * extent, Y: (random.Float64()*2 - 1) * extent}
+	}
+
+	for i := 0; i < 50000; i++ {
+		box := boxBody(10+random.Float64()*90, 10+random.Float64()*90, vector.Vec2{}, random.Float64()*2*math.Pi)
+		center := point(100)
+		half := point(60)
+		capsule := capsuleBody(center.Subtract(half), center.Add(half), 2+random.Float64()*20)
+		checkPolygonCapsule(t, "random", box, capsule)
+	}
+}
@@ -1,59 +1,240 @@
 package collision
 
 import (
+	"engine/constants"
 	"engine/entities"
 	"engine/renderer"
 	"engine/vector"
 	"math"
 )
 
+/*
+Contact manifold between two bodies. All the contacts share the same normal,
+which always points from A to B. Polygons resting on each other can touch in
+up to two points, everything else produces a single contact.
+*/
 type Collision struct {
-	BodyA  *entities.Body
-	BodyB  *entities.Body
-	Normal vector.Vec2
-	Start  vector.Vec2
-	End    vector.Vec2
-	Depth  float64
+	BodyA    *entities.Body
+	BodyB    *entities.Body
+	Normal   vector.Vec2
+	Contacts []Contact
+
+	// Index of the fixtures that touch in BodyA.Fixtures() and BodyB.Fixtures(), 0 for bodies with a single shape
+	FixtureA int
+	FixtureB int
+
+	// Mixed from both bodies by Detect, they can be changed before the collision is solved
+	Restitution     float64
+	StaticFriction  float64
+	DynamicFriction float64
+	TangentSpeed    float64 // of the surfaces relative to each other, along the tangent (see Body.SurfaceVelocity)
+
+	// Skips the collision for this step, the bodies go through each other
+	Disabled bool
+	// Going through a one-way platform, skipped until the bodies stop touching (see UpdateOneWay)
+	PassThrough bool
+	// The bodies overlap, the contacts are not only speculative (see UpdateTouching)
+	Touching bool
 }
 
-func Resolve(bodyA *entities.Body, bodyB *entities.Body) *Collision {
-	if bodyA.Static && bodyB.Static {
-		return nil
-	}
+type Contact struct {
+	Start vector.Vec2 // deepest point of B inside of A
+	End   vector.Vec2 // deepest point of A inside of B
+	Depth float64
+	ID    FeatureID
+
+	// Impulses accumulated by the solver, positive values push the bodies apart
+	NormalImpulse  float64
+	TangentImpulse float64
+
+	// Solver data that stays the same for all the iterations of a step
+	ra           vector.Vec2
+	rb           vector.Vec2
+	normalMass   float64
+	tangentMass  float64
+	velocityBias float64
+	friction     float64 // static or dynamic coefficient, depending on whether the contact slides
+
+	// Position correction (see PositionCorrection)
+	positionBias    float64
+	positionImpulse float64
+	localA          vector.Vec2 // End in A's local space
+	localB          vector.Vec2 // Start in B's local space
+	localNormal     vector.Vec2 // normal in A's local space
+}
 
-	circleA, isCircleA := bodyA.Shape.(*entities.Circle)
-	circleB, isCircleB := bodyB.Shape.(*entities.Circle)
+/*
+Identifies the edges and vertices that produced a contact. As long as the
+same features keep touching the contact keeps the same ID from one step to
+the next, that is how the solver knows it is the same contact.
+*/
+type FeatureID struct {
+	ReferenceEdge int // edge that owns the normal
+	IncidentEdge  int
+	Vertex        int // vertex of the incident edge, negative if created by clipping
+	Flip          bool
+}
 
-	polygonA, isPolygonA := bodyA.Shape.(*entities.Polygon)
-	polygonB, isPolygonB := bodyB.Shape.(*entities.Polygon)
+// Identifies the pair of fixtures of a collision across steps.
+type PairKey struct {
+	BodyA    *entities.Body
+	BodyB    *entities.Body
+	FixtureA int
+	FixtureB int
+}
 
-	var collision *Collision
-	if isCircleA && isCircleB {
-		collision = calculateCirCleCirCleCollission(bodyA, bodyB, circleA, circleB)
+func (collision *Collision) Key() PairKey {
+	return PairKey{
+		BodyA:    collision.BodyA,
+		BodyB:    collision.BodyB,
+		FixtureA: collision.FixtureA,
+		FixtureB: collision.FixtureB,
 	}
+}
 
-	if isPolygonA && isPolygonB {
-		collision = calculatePolygonPolygonCollision(bodyA, bodyB, polygonA, polygonB)
+/*
+Decides if the bodies touch. Collisions also keep the points that are closer
+than CONTACT_MARGIN so the solver can stop the bodies before they meet
+(speculative contacts), those don't count. The bodies start touching once
+they overlap and keep touching until they move apart by more than a tenth of
+a pixel, resting bodies hover around a depth of 0 and would start and stop
+touching every other step. previous is the collision of the last step, nil
+if there wasn't one.
+*/
+func (collision *Collision) UpdateTouching(previous *Collision) {
+	const separationTolerance = 0.1 // pix
+
+	threshold := 0.0
+	if previous != nil && previous.Touching {
+		threshold = -separationTolerance
 	}
 
-	if isPolygonA && isCircleB {
-		collision = calculatePolygonCircleCollision(bodyA, bodyB, polygonA, circleB)
+	collision.Touching = false
+	for _, contact := range collision.Contacts {
+		if contact.Depth > threshold {
+			collision.Touching = true
+			return
+		}
 	}
+}
 
-	if isCircleA && isPolygonB {
-		collision = calculatePolygonCircleCollision(bodyB, bodyA, polygonB, circleA)
+/*
+Copies the impulses accumulated on the previous step by the contacts that
+are still touching. The solver starts from them instead of from zero so
+resting bodies only need a couple of iterations to converge.
+*/
+func (collision *Collision) WarmStart(previous *Collision) {
+	for i := range collision.Contacts {
+		contact := &collision.Contacts[i]
+
+		for _, old := range previous.Contacts {
+			if old.ID == contact.ID {
+				contact.NormalImpulse = old.NormalImpulse
+				contact.TangentImpulse = old.TangentImpulse
+				break
+			}
+		}
 	}
+}
+
+/*
+Narrowphase, returns the contact manifolds between both bodies, empty if they
+are not touching. Every pair of fixtures is tested on its own and gets its
+own collision (with their normal, materials...), but the impulses of all of
+them go to the bodies.
+
+Any two bodies are tested, even if neither of them is dynamic (sensors and
+queries need those), the world skips the pairs it can't solve.
+*/
+func Detect(bodyA *entities.Body, bodyB *entities.Body) []*Collision {
+	var collisions []*Collision
+	fixturesB := bodyB.Fixtures()
+	for i, fixtureA := range bodyA.Fixtures() {
+		for j, fixtureB := range fixturesB {
+			if !fixtureA.Filter.ShouldCollide(&fixtureB.Filter) {
+				continue
+			}
+
+			// Long chains have a fixture per segment, most of them are far away
+			aabbA := fixtureA.Shape.GetAABB()
+			aabbA = aabbA.Expand(constants.CONTACT_MARGIN)
+			if !aabbA.Overlaps(fixtureB.Shape.GetAABB()) {
+				continue
+			}
+
+			collision := detectShapes(fixtureA.Shape, fixtureB.Shape)
+			if collision == nil {
+				continue
+			}
 
-	if collision == nil {
+			collision.BodyA = bodyA
+			collision.BodyB = bodyB
+			collision.FixtureA = i
+			collision.FixtureB = j
+			collision.Restitution = math.Min(fixtureA.E, fixtureB.E)
+			collision.StaticFriction, collision.DynamicFriction = entities.CombineFriction(fixtureA, fixtureB)
+			collision.TangentSpeed = tangentSpeed(bodyA, bodyB)
+			collisions = append(collisions, collision)
+		}
+	}
+	return collisions
+}
+
+/*
+Detects and solves the collisions between both bodies on the spot, the
+penetration is removed straight away. Returns the first collision, nil if
+they don't touch. Deprecated: solving pairs one by one makes stacks depend
+on the order of the bodies, World gathers the collisions of the step with
+Detect and solves them together (see Solver).
+*/
+func Resolve(bodyA *entities.Body, bodyB *entities.Body) *Collision {
+	// Bodies with infinite mass can't push each other
+	if !bodyA.IsDynamic() && !bodyB.IsDynamic() {
 		return nil
 	}
 
-	resolvePenetration(collision)
-	resolveImpulse(collision)
-	return collision
+	collisions := Detect(bodyA, bodyB)
+	if len(collisions) == 0 {
+		return nil
+	}
+
+	// There is no step here, the one of a frame is only used for the speculative contacts
+	solver := NewSolver(constants.VELOCITY_ITERATIONS)
+	solver.WarmStarting = false
+	solver.Correction = CORRECTION_NGS
+	solver.Beta = 1
+	solver.Solve(collisions, 1/float64(constants.FPS))
+	solver.CorrectPositions()
+	return collisions[0]
+}
+
+// Any pair without a registered routine goes through GJK/EPA (see Register).
+func detectShapes(shapeA entities.Shape, shapeB entities.Shape) *Collision {
+	if detect, ok := lookup(shapeA, shapeB); ok {
+		return detect(shapeA, shapeB)
+	}
+	return calculateGJKCollision(shapeA, shapeB)
+}
+
+/*
+The surface of a body moves clockwise, at the contact that is along the
+normal rotated 90 degrees:
+
+	surface = SurfaceVelocity * (-n.y, n.x)
+
+n is the outward normal of the body, Normal for A and -Normal for B. Along
+the tangent the solver uses, (n.y, -n.x), the surface of B moves relative to
+the surface of A at
+
+	surfaceB . tangent - surfaceA . tangent = B.SurfaceVelocity + A.SurfaceVelocity
+
+so the order of the bodies doesn't matter.
+*/
+func tangentSpeed(bodyA *entities.Body, bodyB *entities.Body) float64 {
+	return bodyA.SurfaceVelocity + bodyB.SurfaceVelocity
 }
 
-func calculatePolygonCircleCollision(polygon *entities.Body, circle *entities.Body, polygonShape *entities.Polygon, circleShape *entities.Circle) *Collision {
+func calculatePolygonCircleCollision(polygonShape *entities.Polygon, circleShape *entities.Circle) *Collision {
 
 	closestDistance := math.Inf(-1)
 	closestVertexIdx := -1
@@ -63,7 +244,7 @@ func calculatePolygonCircleCollision(polygon *entities.Body, circle *entities.Bo
 	for idx, vertex := range polygonShape.WorldVertices {
 		edge := polygonShape.EdgeAt(idx)
 		normal := edge.Normal()
-		d := circle.Position.Subtract(vertex)
+		d := circleShape.Center.Subtract(vertex)
 		proj := d.ProjOnTo(normal)
 
 		projections = append(projections, proj)
@@ -75,7 +256,8 @@ func calculatePolygonCircleCollision(polygon *entities.Body, circle *entities.Bo
 		}
 	}
 
-	if closestDistance > float64(circleShape.Radius) {
+	// Circles that are almost touching still produce contacts (see CONTACT_MARGIN)
+	if closestDistance > float64(circleShape.Radius)+constants.CONTACT_MARGIN {
 		return nil
 	}
 
@@ -87,127 +269,128 @@ func calculatePolygonCircleCollision(polygon *entities.Body, circle *entities.Bo
 	var start vector.Vec2
 	var end vector.Vec2
 	var depth float64
+	id := FeatureID{ReferenceEdge: closestVertexIdx, Vertex: -1}
 
 	if closestDistance < 0 {
 		closestEdge := polygonShape.EdgeAt(closestVertexIdx)
 		normal = closestEdge.Normal()
-		start = circle.Position.Subtract(normal.Multiply(float64(circleShape.Radius)))
+		start = circleShape.Center.Subtract(normal.Multiply(float64(circleShape.Radius)))
 		depth = float64(circleShape.Radius) - closestDistance
 		end = start.Add(normal.Multiply(depth))
 	} else if projections[prevIdex] > 0 {
-		normal = circle.Position.Subtract(closestVertex)
+		normal = circleShape.Center.Subtract(closestVertex)
+		distance := normal.Magnitude()
+		if distance > float64(circleShape.Radius)+constants.CONTACT_MARGIN {
+			return nil
+		}
 		normal = normal.Unit()
-		start = circle.Position.Subtract(normal.Multiply(float64(circleShape.Radius)))
+		start = circleShape.Center.Subtract(normal.Multiply(float64(circleShape.Radius)))
 		end = closestVertex
-		depthVec := start.Subtract(end)
-		depth = depthVec.Magnitude()
+		depth = float64(circleShape.Radius) - distance
+		id = FeatureID{ReferenceEdge: -1, Vertex: closestVertexIdx}
 	} else if projections[nextIdx] > 0 {
 		nextVertex := polygonShape.WorldVertices[nextIdx]
-		normal = circle.Position.Subtract(nextVertex)
+		normal = circleShape.Center.Subtract(nextVertex)
+		distance := normal.Magnitude()
+		if distance > float64(circleShape.Radius)+constants.CONTACT_MARGIN {
+			return nil
+		}
 		normal = normal.Unit()
-		start = circle.Position.Subtract(normal.Multiply(float64(circleShape.Radius)))
+		start = circleShape.Center.Subtract(normal.Multiply(float64(circleShape.Radius)))
 		end = nextVertex
-		depthVec := start.Subtract(end)
-		depth = depthVec.Magnitude()
+		depth = float64(circleShape.Radius) - distance
+		id = FeatureID{ReferenceEdge: -1, Vertex: nextIdx}
 	} else {
 		normal = polygonShape.EdgeAt(closestVertexIdx)
 		normal = normal.Normal()
-		start = circle.Position.Subtract(normal.Multiply(float64(circleShape.Radius)))
+		start = circleShape.Center.Subtract(normal.Multiply(float64(circleShape.Radius)))
 		depth = float64(circleShape.Radius) - closestDistance
 		end = start.Add(normal.Multiply(depth))
 	}
 
 	collision = Collision{
-		BodyA:  polygon,
-		BodyB:  circle,
-		Normal: normal,
-		Start:  start,
-		End:    end,
-		Depth:  depth,
+		Normal:   normal,
+		Contacts: []Contact{{Start: start, End: end, Depth: depth, ID: id}},
 	}
 
 	return &collision
 }
 
-func calculateCirCleCirCleCollission(bodyA *entities.Body, bodyB *entities.Body, circleA *entities.Circle, circleB *entities.Circle) *Collision {
-	d := bodyB.Position.Subtract(bodyA.Position)
+func calculateCirCleCirCleCollission(circleA *entities.Circle, circleB *entities.Circle) *Collision {
+	d := circleB.Center.Subtract(circleA.Center)
 	distanceAB := d.Magnitude()
-	if distanceAB > float64((circleA.Radius + circleB.Radius)) {
+	if distanceAB > float64((circleA.Radius+circleB.Radius))+constants.CONTACT_MARGIN {
 		return nil
 	}
 
 	collisionNormal := d.Unit()
 
-	start := bodyB.Position.Subtract(collisionNormal.Multiply(float64(circleB.Radius)))
-	end := bodyA.Position.Add(collisionNormal.Multiply(float64(circleA.Radius)))
-	dep := end.Subtract(start)
-	depth := dep.Magnitude()
+	start := circleB.Center.Subtract(collisionNormal.Multiply(float64(circleB.Radius)))
+	end := circleA.Center.Add(collisionNormal.Multiply(float64(circleA.Radius)))
+	depth := float64(circleA.Radius+circleB.Radius) - distanceAB
 
 	return &Collision{
-		BodyA:  bodyA,
-		BodyB:  bodyB,
-		Normal: collisionNormal,
-		Start:  start,
-		End:    end,
-		Depth:  depth,
+		Normal:   collisionNormal,
+		Contacts: []Contact{{Start: start, End: end, Depth: depth}},
 	}
 }
 
-func calculatePolygonPolygonCollision(bodyA *entities.Body, bodyB *entities.Body, polygonA *entities.Polygon, polygonB *entities.Polygon) *Collision {
-	penetrationAB, edgeA, vertexB := calculatePenetration(polygonA, polygonB)
-	penetrationBA, edgeB, vertexA := calculatePenetration(polygonB, polygonA)
+func calculatePolygonPolygonCollision(polygonA *entities.Polygon, polygonB *entities.Polygon) *Collision {
+	penetrationAB, edgeA := calculatePenetration(polygonA, polygonB)
+	penetrationBA, edgeB := calculatePenetration(polygonB, polygonA)
 
 	/*
-		If the max penetration for both is positive it means that there was no collision
+		If the max penetration for both is positive it means that there was no collision.
+		Polygons that are almost touching still produce contacts (see CONTACT_MARGIN).
 	*/
-	if penetrationBA >= 0 || penetrationAB >= 0 {
+	if penetrationBA > constants.CONTACT_MARGIN || penetrationAB > constants.CONTACT_MARGIN {
 		return nil
 	}
 
-	var depth float64
-	var normal vector.Vec2
-	var start vector.Vec2
-	var end vector.Vec2
 	/*
 		We want to resolve the smallest penetration in this case the highest value.
 		Highest value (less negative) means less penetration.
 
-		depth needs to be positive because both penetrationAB penetrationBA are negative by definition are negative we need to multiply by -
+		The polygon owning that edge is the reference, the other one is the incident polygon.
+		A is preferred unless B is clearly better, otherwise two resting boxes would swap
+		the reference every other step and the contacts would never persist.
 	*/
-	if penetrationAB >= penetrationBA {
-		depth = -penetrationAB
-		normal = edgeA.Normal()
-		start = vertexB
-		end = vertexB.Add(normal.Multiply(depth))
-	} else {
-		depth = -penetrationBA
-		edgeBNormal := edgeB.Normal()
-		normal = edgeBNormal.Multiply(-1) // We need to go from A->B
-		start = vertexA.Add(edgeBNormal.Multiply(depth))
-		end = vertexA
+	const relativeTolerance = 0.98
+	const absoluteTolerance = 0.05 // pix
+	if penetrationBA <= relativeTolerance*penetrationAB+absoluteTolerance {
+		normal := polygonA.EdgeAt(edgeA)
+		normal = normal.Normal()
+		contacts := clipContacts(polygonA, polygonB, edgeA, false)
+		if len(contacts) == 0 {
+			return nil
+		}
+
+		return &Collision{Normal: normal, Contacts: contacts}
+	}
+
+	edgeBNormal := polygonB.EdgeAt(edgeB)
+	edgeBNormal = edgeBNormal.Normal()
+	contacts := clipContacts(polygonB, polygonA, edgeB, true)
+	if len(contacts) == 0 {
+		return nil
 	}
 
 	return &Collision{
-		BodyA:  bodyA,
-		BodyB:  bodyB,
-		Depth:  depth,
-		Normal: normal,
-		Start:  start,
-		End:    end,
+		Normal:   edgeBNormal.Multiply(-1), // We need to go from A->B
+		Contacts: contacts,
 	}
 }
 
-func calculatePenetration(polygonA *entities.Polygon, polygonB *entities.Polygon) (float64, vector.Vec2, vector.Vec2) {
+// Returns the max penetration of polygonB on polygonA and the index of the edge of A where it happens.
+func calculatePenetration(polygonA *entities.Polygon, polygonB *entities.Polygon) (float64, int) {
 	penetration := float64(math.MinInt)
-	var collidingVertex vector.Vec2
-	var collidingEdge vector.Vec2
+	collidingEdge := -1
 
 	for idx, vertexA := range polygonA.WorldVertices {
 		edge := polygonA.EdgeAt(idx)
 		normal := edge.Normal()
 
 		minPenetration := float64(math.MaxInt)
-		var minVertex vector.Vec2
 		for _, vertexB := range polygonB.WorldVertices {
 			vba := vertexB.Subtract(vertexA)
 			currPenetration := vba.Dot(normal)
@@ -217,124 +400,153 @@ func calculatePenetration(polygonA *entities.Polygon, polygonB *entities.Polygon
 			*/
 			if currPenetration < minPenetration {
 				minPenetration = currPenetration
-				minVertex = vertexB
 			}
 		}
 
 		if minPenetration > penetration {
 			penetration = minPenetration
-			collidingEdge = edge
-			collidingVertex = minVertex
+			collidingEdge = idx
 		}
 	}
 
-	return penetration, collidingEdge, collidingVertex
+	return penetration, collidingEdge
 }
 
-func resolvePenetration(collision *Collision) {
-	bodyA := collision.BodyA
-	bodyB := collision.BodyB
-
-	invMassA := 1 / bodyA.Mass
-	invMassB := 1 / bodyB.Mass
-	invSum := invMassA + invMassB
-	// Calculate the % of penetration
-	da := collision.Depth / invSum * invMassA
-	db := collision.Depth / invSum * invMassB
-
-	// Apply to the bodies using the normal to transform the scalar into a vector.
-	if !bodyA.Static {
-		bodyA.Position = bodyA.Position.Subtract(collision.Normal.Multiply(da))
-		bodyA.Shape.UpdateVertices(bodyA.Position, bodyA.Rotation)
+/*
+Builds the contact points between the reference edge and the incident polygon.
+
+The incident edge is the edge of the incident polygon that faces the reference
+edge the most (its normal is the most opposite). That edge gets clipped against
+the two side planes of the reference edge, so only the part of it that lies
+between them survives:
+
+	    side 1    side 2
+	      |         |
+	------+=========+------  reference edge
+	      |  \      |
+	      |    \    |  <- incident edge after clipping
+	      |      \  |
+	      |        \|
+
+Every clipped point that is behind the reference edge is a contact.
+
+flip is true when the reference polygon is B, in that case the incident points
+belong to A and Start/End need to be swapped around.
+*/
+func clipContacts(reference *entities.Polygon, incident *entities.Polygon, referenceEdgeIdx int, flip bool) []Contact {
+	referenceEdge := reference.EdgeAt(referenceEdgeIdx)
+	referenceNormal := referenceEdge.Normal()
+	tangent := referenceEdge.Unit()
+
+	v1 := reference.WorldVertices[referenceEdgeIdx]
+	v2 := reference.WorldVertices[(referenceEdgeIdx+1)%len(reference.WorldVertices)]
+
+	incidentEdgeIdx := findIncidentEdge(incident, referenceNormal)
+	nextIncidentIdx := (incidentEdgeIdx + 1) % len(incident.WorldVertices)
+	incidentPoints := []clipVertex{
+		{point: incident.WorldVertices[incidentEdgeIdx], vertex: incidentEdgeIdx},
+		{point: incident.WorldVertices[nextIncidentIdx], vertex: nextIncidentIdx},
 	}
 
-	if !bodyB.Static {
-		bodyB.Position = bodyB.Position.Add(collision.Normal.Multiply(db))
-		bodyB.Shape.UpdateVertices(bodyB.Position, bodyB.Rotation)
+	negativeTangent := tangent.Multiply(-1)
+	clipped := clipSegmentToLine(incidentPoints, negativeTangent, -tangent.Dot(v1), -1)
+	if len(clipped) < 2 {
+		return nil
 	}
-}
-
-// func resolveImpulse(collision *Collision, bodyA *entities.Body, bodyB *entities.Body) {
-// 	e := math.Min(bodyA.E, bodyB.E)
-// 	relativeVelocity := bodyA.Velocity.Subtract(bodyB.Velocity)
-// 	velDotNormal := relativeVelocity.Dot(collision.Normal)
 
-// 	invMassA := 1.0 / bodyA.Mass
-// 	invMassB := 1.0 / bodyB.Mass
-
-// 	invMassSum := invMassA + invMassB
+	clipped = clipSegmentToLine(clipped, tangent, tangent.Dot(v2), -2)
+	if len(clipped) < 2 {
+		return nil
+	}
 
-// 	jMag := -(1 + e) * velDotNormal / invMassSum
+	var contacts []Contact
+	for _, clippedVertex := range clipped {
+		point := clippedVertex.point
+		d := point.Subtract(v1)
+		separation := d.Dot(referenceNormal)
+		if separation > constants.CONTACT_MARGIN {
+			continue
+		}
 
-// 	impulse := collision.Normal.Multiply(jMag)
+		depth := -separation
+		// projection of the incident point on the reference edge
+		onReference := point.Add(referenceNormal.Multiply(depth))
+		id := FeatureID{
+			ReferenceEdge: referenceEdgeIdx,
+			IncidentEdge:  incidentEdgeIdx,
+			Vertex:        clippedVertex.vertex,
+			Flip:          flip,
+		}
 
-// 	if !bodyA.Static {
-// 		bodyA.Velocity = bodyA.Velocity.Add(impulse.Multiply(invMassA))
-// 	}
+		if flip {
+			contacts = append(contacts, Contact{Start: onReference, End: point, Depth: depth, ID: id})
+		} else {
+			contacts = append(contacts, Contact{Start: point, End: onReference, Depth: depth, ID: id})
+		}
+	}
 
-// 	if !bodyB.Static {
-// 		bodyB.Velocity = bodyB.Velocity.Subtract(impulse.Multiply(invMassB))
-// 	}
-// }
+	return contacts
+}
 
-func resolveImpulse(collision *Collision) {
-	bodyA := collision.BodyA
-	bodyB := collision.BodyB
+func findIncidentEdge(incident *entities.Polygon, referenceNormal vector.Vec2) int {
+	incidentEdgeIdx := -1
+	minDot := math.Inf(1)
 
-	e := math.Min(bodyA.E, bodyB.E)
+	for idx := range incident.WorldVertices {
+		edge := incident.EdgeAt(idx)
+		normal := edge.Normal()
+		dot := normal.Dot(referenceNormal)
+		if dot < minDot {
+			minDot = dot
+			incidentEdgeIdx = idx
+		}
+	}
 
-	// r is the distance from the center of mass to the point of collision aprox.
-	ra := collision.End.Subtract(bodyA.Position)
-	rb := collision.Start.Subtract(bodyB.Position)
+	return incidentEdgeIdx
+}
 
-	// V = v + w X r at the point of contact determined by r
-	Va := bodyA.Velocity.Add(bodyA.AngularVelocityProduct(ra))
-	Vb := bodyB.Velocity.Add(bodyB.AngularVelocityProduct(rb))
-	// vrel = Va - Vb
-	vRel := Va.Subtract(Vb)
-	// Does not depend on the direction
+// Point of the incident edge and the vertex it comes from.
+type clipVertex struct {
+	point  vector.Vec2
+	vertex int
+}
 
-	Jn := calculateImpulse(bodyA, bodyB, vRel, ra, rb, collision.Normal, e)
-	Jt := calculateImpulse(bodyA, bodyB, vRel, ra, rb, collision.Normal.Normal(), e)
-	f := math.Min(bodyA.F, bodyB.F)
-	Jt = Jt.Multiply(f)
+/*
+Keeps the part of the segment where point . normal <= offset. When the segment
+crosses the line the intersection point replaces the discarded end, and it
+gets side as its vertex.
+*/
+func clipSegmentToLine(points []clipVertex, normal vector.Vec2, offset float64, side int) []clipVertex {
+	var clipped []clipVertex
 
-	J := Jn.Add(Jt)
+	distance0 := normal.Dot(points[0].point) - offset
+	distance1 := normal.Dot(points[1].point) - offset
 
-	if !bodyA.Static {
-		bodyA.ApplyImpulse(J, ra)
+	if distance0 <= 0 {
+		clipped = append(clipped, points[0])
 	}
 
-	if !bodyB.Static {
-		bodyB.ApplyImpulse(J.Multiply(-1), rb)
+	if distance1 <= 0 {
+		clipped = append(clipped, points[1])
 	}
-}
-
-func calculateImpulse(bodyA *entities.Body, bodyB *entities.Body, vRel vector.Vec2, ra vector.Vec2, rb vector.Vec2, direction vector.Vec2, e float64) vector.Vec2 {
-	/*
-	                       -(1 + e)(Vrel . dir)
-	   Jn =  ---------------------------------------------
-	            1        1     (ra X dir)^2     (rb x dir)^2
-	         ------- + ----- + ----------- + ------------
-	            Ma       Mb        Ia             Ib
-	*/
-	vRelDirection := vRel.Dot(direction)
 
-	num := -(1 + e) * vRelDirection
-	linearDen := bodyA.InvMass + bodyB.InvMass
-	angularDenA := ra.Cross(direction) * ra.Cross(direction) / bodyA.Shape.MomentOfInertia()
-	angularDenB := rb.Cross(direction) * rb.Cross(direction) / bodyB.Shape.MomentOfInertia()
+	if distance0*distance1 < 0 {
+		t := distance0 / (distance0 - distance1)
+		segment := points[1].point.Subtract(points[0].point)
+		clipped = append(clipped, clipVertex{point: points[0].point.Add(segment.Multiply(t)), vertex: side})
+	}
 
-	JMag := num / (linearDen + angularDenA + angularDenB)
-	return direction.Multiply(JMag)
+	return clipped
 }
 
 func PolygonPolygonCollisionDebugger(collision *Collision, rend renderer.Renderer) {
 	if collision != nil {
-		rend.DrawFilledCircle(int32(collision.Start.X), int32(collision.Start.Y), 2, renderer.GREEN)
-		rend.DrawFilledCircle(int32(collision.End.X), int32(collision.End.Y), 2, renderer.RED)
+		for _, contact := range collision.Contacts {
+			rend.DrawFilledCircle(int32(contact.Start.X), int32(contact.Start.Y), 2, renderer.GREEN)
+			rend.DrawFilledCircle(int32(contact.End.X), int32(contact.End.Y), 2, renderer.RED)
 
-		drawEnd := collision.Start.Add(collision.Normal.Multiply(15))
-		rend.DrawLine(collision.Start, drawEnd, renderer.RED)
+			drawEnd := contact.Start.Add(collision.Normal.Multiply(15))
+			rend.DrawLine(contact.Start, drawEnd, renderer.RED)
+		}
 	}
 }
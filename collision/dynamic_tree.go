@@ -0,0 +1,367 @@
+package collision
+
+import (
+	"engine/constants"
+	"engine/entities"
+)
+
+const nullNode = -1
+
+/*
+Bounding volume hierarchy where every leaf holds the bounding box of one body
+and every internal node holds the union of its two children.
+
+Leaves store a "fat" box (the real box grown by Margin) so small movements
+don't require touching the tree. Only when a body leaves its fat box it gets
+removed and inserted again, and the tree is kept balanced with AVL style
+rotations.
+*/
+type DynamicTree struct {
+	Margin float64
+
+	nodes    []treeNode
+	root     int
+	freeList int
+
+	proxies map[*entities.Body]int // body -> leaf node
+	indices map[*entities.Body]int // body -> index in the world
+	bodies  []*entities.Body
+	aabbs   []entities.AABB
+}
+
+type treeNode struct {
+	aabb   entities.AABB
+	body   *entities.Body
+	parent int
+	left   int
+	right  int
+	height int // 0 for leaves, -1 for free nodes
+}
+
+func (node *treeNode) isLeaf() bool {
+	return node.left == nullNode
+}
+
+func NewDynamicTree(margin float64) *DynamicTree {
+	return &DynamicTree{
+		Margin:   margin,
+		root:     nullNode,
+		freeList: nullNode,
+		proxies:  map[*entities.Body]int{},
+		indices:  map[*entities.Body]int{},
+	}
+}
+
+func (tree *DynamicTree) Update(bodies []*entities.Body) {
+	tree.bodies = bodies
+	tree.aabbs = tree.aabbs[:0]
+	clear(tree.indices)
+
+	for idx, body := range bodies {
+		aabb := body.GetAABB()
+		// Shapes closer than the contact margin still produce contacts
+		aabb = aabb.Expand(constants.CONTACT_MARGIN)
+		tree.aabbs = append(tree.aabbs, aabb)
+		tree.indices[body] = idx
+
+		leaf, ok := tree.proxies[body]
+		if !ok {
+			tree.proxies[body] = tree.insertLeaf(body, aabb.Expand(tree.Margin))
+			continue
+		}
+
+		if tree.nodes[leaf].aabb.Contains(aabb) {
+			continue
+		}
+
+		tree.removeLeaf(leaf)
+		tree.freeNode(leaf)
+		tree.proxies[body] = tree.insertLeaf(body, aabb.Expand(tree.Margin))
+	}
+
+	// Bodies that are not in the world anymore
+	for body, leaf := range tree.proxies {
+		if _, ok := tree.indices[body]; ok {
+			continue
+		}
+		tree.removeLeaf(leaf)
+		tree.freeNode(leaf)
+		delete(tree.proxies, body)
+	}
+}
+
+func (tree *DynamicTree) Pairs() []Pair {
+	found := map[indexPair]bool{}
+
+	for idx, body := range tree.bodies {
+		leaf := tree.proxies[body]
+		tree.Query(tree.nodes[leaf].aabb, func(other *entities.Body) bool {
+			otherIdx := tree.indices[other]
+			if otherIdx == idx {
+				return true
+			}
+
+			key := newIndexPair(idx, otherIdx)
+			if !found[key] && tree.aabbs[key.a].Overlaps(tree.aabbs[key.b]) {
+				found[key] = true
+			}
+			return true
+		})
+	}
+
+	return sortedPairs(found, tree.bodies)
+}
+
+// Calls callback for every body whose fat box overlaps aabb. Returning false stops the search.
+func (tree *DynamicTree) Query(aabb entities.AABB, callback func(body *entities.Body) bool) {
+	if tree.root == nullNode {
+		return
+	}
+
+	stack := []int{tree.root}
+	for len(stack) > 0 {
+		index := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		node := &tree.nodes[index]
+		if !node.aabb.Overlaps(aabb) {
+			continue
+		}
+
+		if node.isLeaf() {
+			if !callback(node.body) {
+				return
+			}
+			continue
+		}
+
+		stack = append(stack, node.left, node.right)
+	}
+}
+
+func (tree *DynamicTree) allocateNode() int {
+	if tree.freeList == nullNode {
+		tree.nodes = append(tree.nodes, treeNode{})
+		tree.freeList = len(tree.nodes) - 1
+		tree.nodes[tree.freeList].parent = nullNode
+	}
+
+	index := tree.freeList
+	tree.freeList = tree.nodes[index].parent
+	tree.nodes[index] = treeNode{
+		parent: nullNode,
+		left:   nullNode,
+		right:  nullNode,
+	}
+	return index
+}
+
+func (tree *DynamicTree) freeNode(index int) {
+	// Free nodes reuse the parent field as the next pointer of the free list
+	tree.nodes[index] = treeNode{parent: tree.freeList, height: -1}
+	tree.freeList = index
+}
+
+func (tree *DynamicTree) insertLeaf(body *entities.Body, aabb entities.AABB) int {
+	leaf := tree.allocateNode()
+	tree.nodes[leaf].aabb = aabb
+	tree.nodes[leaf].body = body
+
+	if tree.root == nullNode {
+		tree.root = leaf
+		return leaf
+	}
+
+	/*
+		Walk down picking the child that grows the less (surface area heuristic)
+		until it is cheaper to create a new parent right here.
+	*/
+	index := tree.root
+	for !tree.nodes[index].isLeaf() {
+		node := tree.nodes[index]
+
+		combined := node.aabb.Union(aabb)
+		combinedCost := combined.Perimeter()
+		cost := 2 * combinedCost
+		// cost of pushing the leaf further down
+		inheritanceCost := 2 * (combinedCost - node.aabb.Perimeter())
+
+		costLeft := tree.descendCost(node.left, aabb) + inheritanceCost
+		costRight := tree.descendCost(node.right, aabb) + inheritanceCost
+
+		if cost < costLeft && cost < costRight {
+			break
+		}
+
+		if costLeft < costRight {
+			index = node.left
+		} else {
+			index = node.right
+		}
+	}
+
+	sibling := index
+	oldParent := tree.nodes[sibling].parent
+	newParent := tree.allocateNode()
+
+	tree.nodes[newParent].parent = oldParent
+	tree.nodes[newParent].aabb = aabb.Union(tree.nodes[sibling].aabb)
+	tree.nodes[newParent].height = tree.nodes[sibling].height + 1
+	tree.nodes[newParent].left = sibling
+	tree.nodes[newParent].right = leaf
+	tree.nodes[sibling].parent = newParent
+	tree.nodes[leaf].parent = newParent
+
+	if oldParent == nullNode {
+		tree.root = newParent
+	} else if tree.nodes[oldParent].left == sibling {
+		tree.nodes[oldParent].left = newParent
+	} else {
+		tree.nodes[oldParent].right = newParent
+	}
+
+	tree.refit(tree.nodes[leaf].parent)
+	return leaf
+}
+
+func (tree *DynamicTree) descendCost(index int, aabb entities.AABB) float64 {
+	node := &tree.nodes[index]
+	combined := aabb.Union(node.aabb)
+
+	if node.isLeaf() {
+		return combined.Perimeter()
+	}
+	return combined.Perimeter() - node.aabb.Perimeter()
+}
+
+func (tree *DynamicTree) removeLeaf(leaf int) {
+	if leaf == tree.root {
+		tree.root = nullNode
+		return
+	}
+
+	parent := tree.nodes[leaf].parent
+	grandParent := tree.nodes[parent].parent
+
+	sibling := tree.nodes[parent].left
+	if sibling == leaf {
+		sibling = tree.nodes[parent].right
+	}
+
+	tree.nodes[sibling].parent = grandParent
+	tree.freeNode(parent)
+
+	if grandParent == nullNode {
+		tree.root = sibling
+		return
+	}
+
+	if tree.nodes[grandParent].left == parent {
+		tree.nodes[grandParent].left = sibling
+	} else {
+		tree.nodes[grandParent].right = sibling
+	}
+
+	tree.refit(grandParent)
+}
+
+// Walks up to the root rebalancing and recomputing the boxes and heights.
+func (tree *DynamicTree) refit(index int) {
+	for index != nullNode {
+		index = tree.balance(index)
+
+		node := &tree.nodes[index]
+		left := &tree.nodes[node.left]
+		right := &tree.nodes[node.right]
+
+		node.height = 1 + max(left.height, right.height)
+		node.aabb = left.aabb.Union(right.aabb)
+
+		index = node.parent
+	}
+}
+
+/*
+If one of the children of a is more than one level taller than the other,
+that child is rotated up and takes the place of a.
+
+	  a              c
+	 / \            / \
+	b   c    =>    a   g
+	   / \        / \
+	  f   g      b   f
+
+Returns the index of the node that ends up in the place of a.
+*/
+func (tree *DynamicTree) balance(iA int) int {
+	a := &tree.nodes[iA]
+	if a.isLeaf() || a.height < 2 {
+		return iA
+	}
+
+	iB := a.left
+	iC := a.right
+	b := &tree.nodes[iB]
+	c := &tree.nodes[iC]
+
+	balance := c.height - b.height
+
+	if balance > 1 {
+		return tree.rotateUp(iA, iC, false)
+	}
+
+	if balance < -1 {
+		return tree.rotateUp(iA, iB, true)
+	}
+
+	return iA
+}
+
+// Moves the child iUp into the place of iA. isLeft tells which child of a it currently is.
+func (tree *DynamicTree) rotateUp(iA int, iUp int, isLeft bool) int {
+	a := &tree.nodes[iA]
+	up := &tree.nodes[iUp]
+
+	iF := up.left
+	iG := up.right
+	f := &tree.nodes[iF]
+	g := &tree.nodes[iG]
+
+	// up takes the place of a
+	up.left = iA
+	up.parent = a.parent
+	a.parent = iUp
+
+	if up.parent == nullNode {
+		tree.root = iUp
+	} else if tree.nodes[up.parent].left == iA {
+		tree.nodes[up.parent].left = iUp
+	} else {
+		tree.nodes[up.parent].right = iUp
+	}
+
+	// The tallest grandchild stays with up, the other one goes down to a
+	iKeep, iMove := iF, iG
+	if f.height <= g.height {
+		iKeep, iMove = iG, iF
+	}
+	up.right = iKeep
+	tree.nodes[iMove].parent = iA
+
+	if isLeft {
+		a.left = iMove
+	} else {
+		a.right = iMove
+	}
+
+	left := &tree.nodes[a.left]
+	right := &tree.nodes[a.right]
+	a.aabb = left.aabb.Union(right.aabb)
+	a.height = 1 + max(left.height, right.height)
+
+	keep := &tree.nodes[iKeep]
+	up.aabb = a.aabb.Union(keep.aabb)
+	up.height = 1 + max(a.height, keep.height)
+
+	return iUp
+}
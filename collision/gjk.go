@@ -0,0 +1,276 @@
+package collision
+
+import (
+	"engine/constants"
+	"engine/entities"
+	"engine/vector"
+	"math"
+)
+
+/*
+Generic narrowphase for any pair of convex shapes, all it needs is the
+support function of each shape (see entities.Shape).
+
+Both algorithms work on the Minkowski difference of the shapes, A - B, the
+set of every point of A minus every point of B. A and B overlap if and only
+if A - B contains the origin, and the distance between the shapes is the
+distance from the origin to A - B. Its support function comes for free from
+the ones of the shapes: support(d) = A.Support(d) - B.Support(-d).
+
+GJK walks a simplex (point, segment, triangle) of A - B towards the origin.
+It either encloses the origin (overlap) or finds the closest point to it
+(distance). EPA starts from the GJK triangle and expands it until it finds
+the edge of A - B closest to the origin, that edge gives the normal and the
+depth.
+*/
+func calculateGJKCollision(shapeA entities.Shape, shapeB entities.Shape) *Collision {
+	simplex, closest, intersecting := gjk(shapeA, shapeB)
+
+	var normal vector.Vec2
+	var depth float64
+	if intersecting {
+		normal, depth = epa(shapeA, shapeB, simplex)
+	} else {
+		// Shapes that are almost touching still produce contacts (see CONTACT_MARGIN)
+		distance := closest.Magnitude()
+		if distance > constants.CONTACT_MARGIN {
+			return nil
+		}
+		normal = closest.Multiply(-1 / distance)
+		depth = -distance
+	}
+
+	start := shapeB.Support(normal.Multiply(-1))
+	end := start.Add(normal.Multiply(depth))
+
+	return &Collision{
+		Normal:   normal,
+		Contacts: []Contact{{Start: start, End: end, Depth: depth}},
+	}
+}
+
+// Distance between two convex shapes, 0 and true if they overlap.
+func Distance(shapeA entities.Shape, shapeB entities.Shape) (float64, bool) {
+	_, closest, intersecting := gjk(shapeA, shapeB)
+	if intersecting {
+		return 0, true
+	}
+	return closest.Magnitude(), false
+}
+
+// Anything with a support function, entities.Shape among them.
+type convex interface {
+	Support(direction vector.Vec2) vector.Vec2
+}
+
+// A single point as a convex shape, used to cast rays with GJK.
+type pointShape struct {
+	position vector.Vec2
+}
+
+func (shape *pointShape) Support(direction vector.Vec2) vector.Vec2 {
+	return shape.position
+}
+
+func minkowskiSupport(shapeA convex, shapeB convex, direction vector.Vec2) vector.Vec2 {
+	a := shapeA.Support(direction)
+	b := shapeB.Support(direction.Multiply(-1))
+	return a.Subtract(b)
+}
+
+/*
+Returns the last simplex and the point of A - B closest to the origin. When
+the shapes overlap the closest point is meaningless and the simplex is the
+triangle (or what is left of it for shapes that only touch) EPA starts from.
+*/
+func gjk(shapeA convex, shapeB convex) ([]vector.Vec2, vector.Vec2, bool) {
+	simplex := []vector.Vec2{minkowskiSupport(shapeA, shapeB, vector.Vec2{X: 1, Y: 0})}
+	var closest vector.Vec2
+
+	for i := 0; i < constants.GJK_ITERATIONS; i++ {
+		var inside bool
+		simplex, closest, inside = reduceSimplex(simplex)
+		if inside || closest.Dot(closest) < constants.GJK_TOLERANCE*constants.GJK_TOLERANCE {
+			return simplex, closest, true
+		}
+
+		// Look for a point of A - B further towards the origin
+		direction := closest.Multiply(-1)
+		w := minkowskiSupport(shapeA, shapeB, direction)
+
+		/*
+			If the new point doesn't get any closer than the current one along
+			direction there is nothing closer, closest is the answer.
+		*/
+		progress := (w.Dot(direction) - closest.Dot(direction)) / direction.Magnitude()
+		if progress < constants.GJK_TOLERANCE {
+			return simplex, closest, false
+		}
+
+		simplex = append(simplex, w)
+	}
+
+	return simplex, closest, false
+}
+
+/*
+Finds the point of the simplex closest to the origin and drops the vertices
+that are not needed to describe it. A triangle that contains the origin is
+kept whole.
+*/
+func reduceSimplex(simplex []vector.Vec2) ([]vector.Vec2, vector.Vec2, bool) {
+	switch len(simplex) {
+	case 1:
+		return simplex, simplex[0], false
+	case 2:
+		reduced, closest := closestOnSegment(simplex[0], simplex[1])
+		return reduced, closest, false
+	}
+
+	a, b, c := simplex[0], simplex[1], simplex[2]
+
+	// The origin is inside if it is on the same side of the three edges
+	ab := b.Subtract(a)
+	bc := c.Subtract(b)
+	ca := a.Subtract(c)
+	o := vector.Vec2{}
+	sideAB := ab.Cross(o.Subtract(a))
+	sideBC := bc.Cross(o.Subtract(b))
+	sideCA := ca.Cross(o.Subtract(c))
+	if (sideAB >= 0 && sideBC >= 0 && sideCA >= 0) || (sideAB <= 0 && sideBC <= 0 && sideCA <= 0) {
+		return simplex, o, true
+	}
+
+	// Otherwise the closest point is on one of the edges
+	var best []vector.Vec2
+	var closest vector.Vec2
+	bestDistance := math.Inf(1)
+	for _, edge := range [][2]vector.Vec2{{a, b}, {b, c}, {c, a}} {
+		reduced, point := closestOnSegment(edge[0], edge[1])
+		if distance := point.Dot(point); distance < bestDistance {
+			bestDistance = distance
+			best = reduced
+			closest = point
+		}
+	}
+	return best, closest, false
+}
+
+func closestOnSegment(a vector.Vec2, b vector.Vec2) ([]vector.Vec2, vector.Vec2) {
+	ab := b.Subtract(a)
+	lengthSquared := ab.Dot(ab)
+	if lengthSquared == 0 {
+		return []vector.Vec2{a}, a
+	}
+
+	t := -a.Dot(ab) / lengthSquared
+	if t <= 0 {
+		return []vector.Vec2{a}, a
+	}
+	if t >= 1 {
+		return []vector.Vec2{b}, b
+	}
+	return []vector.Vec2{a, b}, a.Add(ab.Multiply(t))
+}
+
+/*
+Expanding polytope algorithm. The edge of the polytope closest to the origin
+is pushed out with the support point along its normal until the support
+point is on the edge already, then that edge is on the boundary of A - B:
+
+	normal: normal of the edge, points from A to B
+	depth:  distance from the origin to the edge
+*/
+func epa(shapeA convex, shapeB convex, simplex []vector.Vec2) (vector.Vec2, float64) {
+	polytope := completeTriangle(shapeA, shapeB, append([]vector.Vec2{}, simplex...))
+	if len(polytope) < 3 {
+		// Degenerated difference, the shapes only touch along a line
+		return edgeNormal(polytope), 0
+	}
+
+	// Wind the polytope so Vec2.Normal gives the outward normals (see Polygon.EdgeAt)
+	ab := polytope[1].Subtract(polytope[0])
+	ac := polytope[2].Subtract(polytope[0])
+	if ab.Cross(ac) < 0 {
+		polytope[1], polytope[2] = polytope[2], polytope[1]
+	}
+
+	var normal vector.Vec2
+	var depth float64
+	for i := 0; i < constants.GJK_ITERATIONS; i++ {
+		closestIdx := 0
+		depth = math.Inf(1)
+		for idx, vertex := range polytope {
+			edge := polytope[(idx+1)%len(polytope)].Subtract(vertex)
+			edgeNormal := edge.Normal()
+			if distance := edgeNormal.Dot(vertex); distance < depth {
+				depth = distance
+				normal = edgeNormal
+				closestIdx = idx
+			}
+		}
+
+		w := minkowskiSupport(shapeA, shapeB, normal)
+		if w.Dot(normal)-depth < constants.GJK_TOLERANCE {
+			break
+		}
+
+		// Split the edge with the new point
+		polytope = append(polytope[:closestIdx+1], append([]vector.Vec2{w}, polytope[closestIdx+1:]...)...)
+	}
+
+	return normal, depth
+}
+
+/*
+GJK stops as soon as the origin is on the simplex, so shapes that are barely
+touching can leave EPA with a point or a segment. The missing vertices are
+taken from the support points along the directions that are not covered.
+*/
+func completeTriangle(shapeA convex, shapeB convex, polytope []vector.Vec2) []vector.Vec2 {
+	directions := []vector.Vec2{{X: 1, Y: 0}, {X: -1, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: -1}}
+	if len(polytope) == 2 {
+		edge := polytope[1].Subtract(polytope[0])
+		normal := edge.Normal()
+		directions = []vector.Vec2{normal, normal.Multiply(-1)}
+	}
+
+	for _, direction := range directions {
+		if len(polytope) == 3 {
+			break
+		}
+
+		w := minkowskiSupport(shapeA, shapeB, direction)
+		if !extendsPolytope(polytope, w) {
+			continue
+		}
+		polytope = append(polytope, w)
+	}
+
+	return polytope
+}
+
+// False if point is already in the polytope or, for a segment, on its line.
+func extendsPolytope(polytope []vector.Vec2, point vector.Vec2) bool {
+	for _, vertex := range polytope {
+		d := point.Subtract(vertex)
+		if d.Dot(d) < constants.GJK_TOLERANCE*constants.GJK_TOLERANCE {
+			return false
+		}
+	}
+
+	if len(polytope) == 2 {
+		ab := polytope[1].Subtract(polytope[0])
+		ap := point.Subtract(polytope[0])
+		return math.Abs(ab.Cross(ap)) > constants.GJK_TOLERANCE
+	}
+	return true
+}
+
+func edgeNormal(polytope []vector.Vec2) vector.Vec2 {
+	if len(polytope) < 2 {
+		return vector.Vec2{X: 0, Y: 1}
+	}
+	edge := polytope[1].Subtract(polytope[0])
+	return edge.Normal()
+}
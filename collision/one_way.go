@@ -0,0 +1,66 @@
+package collision
+
+import (
+	"engine/entities"
+	"engine/vector"
+)
+
+/*
+Direction in which a one-way body is solid, the outward normal of its
+one-way edge in world space. False if the body is solid from everywhere.
+*/
+func OneWayNormal(body *entities.Body) (vector.Vec2, bool) {
+	polygon, ok := body.Shape.(*entities.Polygon)
+	if !ok || !polygon.OneWay {
+		return vector.Vec2{}, false
+	}
+
+	edge := polygon.EdgeAt(polygon.OneWayEdge % len(polygon.WorldVertices))
+	return edge.Normal(), true
+}
+
+/*
+Decides if the bodies go through a one-way platform. The decision is taken
+when they start touching and kept until they stop, otherwise a body jumping
+through the platform would be pushed up (or down) as soon as its deepest
+point changed sides halfway through. previous is the collision of the last
+step, nil if they just started touching.
+
+The bodies pass if the other body doesn't come from the solid side, or if it
+moves out of the platform through it:
+
+	normal . oneWay <= 0 || vRel . oneWay > 0
+
+normal pointing from the platform to the other body and vRel being the
+velocity of the other body relative to the platform.
+*/
+func (collision *Collision) UpdateOneWay(previous *Collision) {
+	if previous != nil {
+		collision.PassThrough = previous.PassThrough
+		return
+	}
+
+	collision.PassThrough = passesThrough(collision, collision.BodyA, collision.BodyB, collision.Normal) ||
+		passesThrough(collision, collision.BodyB, collision.BodyA, collision.Normal.Multiply(-1))
+}
+
+func passesThrough(collision *Collision, platform *entities.Body, other *entities.Body, normal vector.Vec2) bool {
+	oneWay, ok := OneWayNormal(platform)
+	if !ok {
+		return false
+	}
+
+	if normal.Dot(oneWay) <= 0 {
+		return true
+	}
+
+	// Velocities of both bodies at the contact point
+	point := collision.Contacts[0].Start
+	rPlatform := point.Subtract(platform.Position)
+	rOther := point.Subtract(other.Position)
+	vPlatform := platform.Velocity.Add(platform.AngularVelocityProduct(rPlatform))
+	vOther := other.Velocity.Add(other.AngularVelocityProduct(rOther))
+	vRel := vOther.Subtract(vPlatform)
+
+	return vRel.Dot(oneWay) > 0
+}
@@ -0,0 +1,169 @@
+package collision
+
+import (
+	"engine/constants"
+	"engine/entities"
+	"engine/vector"
+	"math"
+)
+
+/*
+How the solver pushes apart bodies that ended up overlapping:
+
+  - CORRECTION_BAUMGARTE: the depth becomes an extra separating velocity
+    (Beta / dt * depth). Cheap, but that velocity is real, so resting bodies
+    get a small bounce when they are pushed out.
+  - CORRECTION_SPLIT_IMPULSE: same bias, but it is solved on a separate
+    pseudo velocity that only moves the bodies for this step and is then
+    thrown away. No energy is added.
+  - CORRECTION_NGS: nonlinear Gauss-Seidel. After the bodies moved the
+    depth is measured again with the new positions and the positions are
+    fixed directly, a few iterations, Box2D style.
+
+In all of them Slop pixels of overlap are left alone, otherwise the bodies
+would separate and collide again every other step.
+*/
+type PositionCorrection int
+
+const (
+	CORRECTION_BAUMGARTE PositionCorrection = iota
+	CORRECTION_SPLIT_IMPULSE
+	CORRECTION_NGS
+)
+
+// Velocity that only exists to remove the penetration (see CORRECTION_SPLIT_IMPULSE).
+type pseudoVelocity struct {
+	Velocity        vector.Vec2
+	AngularVelocity float64
+}
+
+/*
+Moves the bodies out of each other. It has to be called once the velocities
+have been integrated, the split impulse velocities and the NGS corrections
+are applied on top of that movement.
+*/
+func (solver *Solver) CorrectPositions() {
+	switch solver.Correction {
+	case CORRECTION_SPLIT_IMPULSE:
+		for body, pseudo := range solver.pseudoVelocities {
+			body.Position = body.Position.Add(pseudo.Velocity.Multiply(solver.dt))
+			body.Rotation += pseudo.AngularVelocity * solver.dt
+			body.Shape.UpdateVertices(body.Position, body.Rotation)
+		}
+	case CORRECTION_NGS:
+		for i := 0; i < solver.PositionIterations; i++ {
+			for _, collision := range solver.collisions {
+				solver.resolvePosition(collision)
+			}
+		}
+	}
+}
+
+// Same as resolveImpulse but only along the normal and on the pseudo velocities.
+func (solver *Solver) solvePseudoVelocities() {
+	solver.pseudoVelocities = make(map[*entities.Body]*pseudoVelocity)
+
+	for i := 0; i < solver.Iterations; i++ {
+		for _, collision := range solver.collisions {
+			pseudoA := solver.pseudoVelocityOf(collision.BodyA)
+			pseudoB := solver.pseudoVelocityOf(collision.BodyB)
+			normal := collision.Normal
+
+			for j := range collision.Contacts {
+				contact := &collision.Contacts[j]
+
+				Va := pseudoA.Velocity.Add(angularVelocityProduct(pseudoA.AngularVelocity, contact.ra))
+				Vb := pseudoB.Velocity.Add(angularVelocityProduct(pseudoB.AngularVelocity, contact.rb))
+				vRel := Vb.Subtract(Va)
+				jp := -contact.normalMass * (vRel.Dot(normal) - contact.positionBias)
+
+				oldPositionImpulse := contact.positionImpulse
+				contact.positionImpulse = math.Max(oldPositionImpulse+jp, 0)
+				jp = contact.positionImpulse - oldPositionImpulse
+
+				J := normal.Multiply(jp)
+				pseudoA.apply(collision.BodyA, J.Multiply(-1), contact.ra)
+				pseudoB.apply(collision.BodyB, J, contact.rb)
+			}
+		}
+	}
+}
+
+func (solver *Solver) pseudoVelocityOf(body *entities.Body) *pseudoVelocity {
+	pseudo, ok := solver.pseudoVelocities[body]
+	if !ok {
+		pseudo = &pseudoVelocity{}
+		solver.pseudoVelocities[body] = pseudo
+	}
+	return pseudo
+}
+
+func (pseudo *pseudoVelocity) apply(body *entities.Body, J vector.Vec2, r vector.Vec2) {
+	invMass, invInertia := inverseMass(body)
+	pseudo.Velocity = pseudo.Velocity.Add(J.Multiply(invMass))
+	pseudo.AngularVelocity += r.Cross(J) * invInertia
+}
+
+/*
+One NGS pass over the contacts of a collision. The contact points are stored
+in the local space of their bodies, so after the bodies moved we know where
+they are now and how deep they still are:
+
+	depth = (End - Start) . normal
+
+The positions are pushed along the normal by Beta of what is left (minus the
+slop), with the same effective mass used for the impulses.
+*/
+func (solver *Solver) resolvePosition(collision *Collision) {
+	bodyA := collision.BodyA
+	bodyB := collision.BodyB
+
+	for i := range collision.Contacts {
+		contact := &collision.Contacts[i]
+
+		normal := contact.localNormal.Rotate(bodyA.Rotation)
+		end := toWorld(bodyA, contact.localA)
+		start := toWorld(bodyB, contact.localB)
+		ra := end.Subtract(bodyA.Position)
+		rb := start.Subtract(bodyB.Position)
+
+		separation := end.Subtract(start)
+		depth := separation.Dot(normal)
+		correction := solver.Beta * (depth - solver.Slop)
+		correction = math.Max(0, math.Min(correction, constants.MAX_POSITION_CORRECTION))
+		if correction == 0 {
+			continue
+		}
+
+		P := normal.Multiply(correction * effectiveMass(bodyA, bodyB, ra, rb, normal))
+		moveBody(bodyA, P.Multiply(-1), ra)
+		moveBody(bodyB, P, rb)
+	}
+}
+
+// Like an impulse but it changes the position instead of the velocity.
+func moveBody(body *entities.Body, P vector.Vec2, r vector.Vec2) {
+	invMass, invInertia := inverseMass(body)
+	if invMass == 0 && invInertia == 0 {
+		return
+	}
+
+	body.Position = body.Position.Add(P.Multiply(invMass))
+	body.Rotation += r.Cross(P) * invInertia
+	body.Shape.UpdateVertices(body.Position, body.Rotation)
+}
+
+func toLocal(body *entities.Body, point vector.Vec2) vector.Vec2 {
+	r := point.Subtract(body.Position)
+	return r.Rotate(-body.Rotation)
+}
+
+func toWorld(body *entities.Body, local vector.Vec2) vector.Vec2 {
+	r := local.Rotate(body.Rotation)
+	return body.Position.Add(r)
+}
+
+// w X r, see Body.AngularVelocityProduct
+func angularVelocityProduct(w float64, r vector.Vec2) vector.Vec2 {
+	return vector.Vec2{X: -w * r.Y, Y: w * r.X}
+}
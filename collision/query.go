@@ -0,0 +1,51 @@
+package collision
+
+import (
+	"engine/entities"
+	"engine/vector"
+)
+
+// True if point is inside of the shape of the body (or on its boundary).
+func TestPoint(body *entities.Body, point vector.Vec2) bool {
+	for _, fixture := range body.Fixtures() {
+		if testPoint(fixture.Shape, point) {
+			return true
+		}
+	}
+	return false
+}
+
+func testPoint(shape entities.Shape, point vector.Vec2) bool {
+	switch shape := shape.(type) {
+	case *entities.Circle:
+		d := point.Subtract(shape.Center)
+		radius := float64(shape.Radius)
+		return d.Dot(d) <= radius*radius
+	case *entities.Polygon:
+		// Behind every edge
+		for idx, vertex := range shape.WorldVertices {
+			edge := shape.EdgeAt(idx)
+			normal := edge.Normal()
+			d := point.Subtract(vertex)
+			if normal.Dot(d) > 0 {
+				return false
+			}
+		}
+		return true
+	default:
+		_, _, intersecting := gjk(&pointShape{position: point}, shape)
+		return intersecting
+	}
+}
+
+// True if the shapes of the bodies overlap, bodies that are only close (see CONTACT_MARGIN) don't count.
+func TestOverlap(bodyA *entities.Body, bodyB *entities.Body) bool {
+	for _, collision := range Detect(bodyA, bodyB) {
+		for _, contact := range collision.Contacts {
+			if contact.Depth > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
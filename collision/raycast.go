@@ -0,0 +1,195 @@
+package collision
+
+import (
+	"engine/constants"
+	"engine/entities"
+	"engine/vector"
+	"math"
+)
+
+// Half line that starts at Origin and stops after MaxDistance pixels.
+type Ray struct {
+	Origin      vector.Vec2
+	Direction   vector.Vec2 // doesn't need to be a unit vector, but can't be 0
+	MaxDistance float64
+}
+
+/*
+Where a ray hits a body. Fraction is how far along the ray the hit is, 0 at
+the origin and 1 at MaxDistance:
+
+	Point = Origin + Direction.Unit() * MaxDistance * Fraction
+*/
+type RayCastHit struct {
+	Body     *entities.Body
+	Point    vector.Vec2
+	Normal   vector.Vec2 // of the surface that was hit, it points out of the body
+	Fraction float64
+}
+
+/*
+Intersects the ray with the shape of the body, the closest of its fixtures
+for compound bodies. Rays that start inside of the body don't hit it, that
+way a ray shot from inside of a body only sees the rest of the world.
+*/
+func RayCast(body *entities.Body, ray Ray) (RayCastHit, bool) {
+	// A ray without direction goes nowhere
+	if ray.MaxDistance <= 0 || ray.Direction.Magnitude() == 0 {
+		return RayCastHit{}, false
+	}
+	direction := ray.Direction.Unit()
+
+	distance := ray.MaxDistance
+	var normal vector.Vec2
+	hit := false
+	for _, fixture := range body.Fixtures() {
+		// Only the fixtures closer than the closest hit so far matter
+		if d, n, ok := rayCastShape(fixture.Shape, ray.Origin, direction, distance); ok {
+			distance, normal, hit = d, n, true
+		}
+	}
+
+	if !hit {
+		return RayCastHit{}, false
+	}
+
+	return RayCastHit{
+		Body:     body,
+		Point:    ray.Origin.Add(direction.Multiply(distance)),
+		Normal:   normal,
+		Fraction: distance / ray.MaxDistance,
+	}, true
+}
+
+func rayCastShape(shape entities.Shape, origin vector.Vec2, direction vector.Vec2, maxDistance float64) (float64, vector.Vec2, bool) {
+	switch shape := shape.(type) {
+	case *entities.Circle:
+		return rayCastCircle(shape, origin, direction, maxDistance)
+	case *entities.Polygon:
+		return rayCastPolygon(shape, origin, direction, maxDistance)
+	default:
+		return rayCastConvex(shape, origin, direction, maxDistance)
+	}
+}
+
+/*
+Points of the ray are origin + direction * t. They are on the circle when
+
+	|origin + direction * t - center|^2 = radius^2
+
+with s = origin - center that is t^2 + 2 (s . direction) t + s . s - radius^2 = 0,
+the first root is the hit.
+*/
+func rayCastCircle(circle *entities.Circle, origin vector.Vec2, direction vector.Vec2, maxDistance float64) (float64, vector.Vec2, bool) {
+	radius := float64(circle.Radius)
+	s := origin.Subtract(circle.Center)
+	b := s.Dot(direction)
+	c := s.Dot(s) - radius*radius
+
+	discriminant := b*b - c
+	if c < 0 || discriminant < 0 {
+		return 0, vector.Vec2{}, false
+	}
+
+	t := -b - math.Sqrt(discriminant)
+	if t < 0 || t > maxDistance {
+		return 0, vector.Vec2{}, false
+	}
+
+	point := origin.Add(direction.Multiply(t))
+	normal := point.Subtract(circle.Center)
+	return t, normal.Unit(), true
+}
+
+/*
+The polygon is the intersection of the half planes of its edges. The ray is
+clipped against each of them (Cyrus-Beck): edges the ray enters through raise
+the lower bound, edges it leaves through lower the upper bound. If there is
+something left the ray entered the polygon at the lower bound.
+*/
+func rayCastPolygon(polygon *entities.Polygon, origin vector.Vec2, direction vector.Vec2, maxDistance float64) (float64, vector.Vec2, bool) {
+	lower := 0.0
+	upper := maxDistance
+	var normal vector.Vec2
+	entered := false
+
+	for idx, vertex := range polygon.WorldVertices {
+		edge := polygon.EdgeAt(idx)
+		edgeNormal := edge.Normal()
+
+		// origin + direction * t is inside of the edge while numerator - denominator * t >= 0
+		d := vertex.Subtract(origin)
+		numerator := edgeNormal.Dot(d)
+		denominator := edgeNormal.Dot(direction)
+
+		if denominator == 0 {
+			// Parallel to the edge and outside of it
+			if numerator < 0 {
+				return 0, vector.Vec2{}, false
+			}
+			continue
+		}
+
+		t := numerator / denominator
+		if denominator < 0 && t > lower {
+			lower = t
+			normal = edgeNormal
+			entered = true
+		} else if denominator > 0 && t < upper {
+			upper = t
+		}
+
+		if upper < lower {
+			return 0, vector.Vec2{}, false
+		}
+	}
+
+	// The origin is inside of the polygon
+	if !entered {
+		return 0, vector.Vec2{}, false
+	}
+
+	return lower, normal, true
+}
+
+/*
+Any other shape goes through GJK (conservative advancement). GJK gives the
+closest point of the shape and the normal there, the shape is completely
+behind the plane they define, so the ray can safely jump to where it crosses
+that plane. Repeat until the point of the ray touches the shape.
+*/
+func rayCastConvex(shape entities.Shape, origin vector.Vec2, direction vector.Vec2, maxDistance float64) (float64, vector.Vec2, bool) {
+	t := 0.0
+	var normal vector.Vec2
+
+	for i := 0; i < constants.GJK_ITERATIONS; i++ {
+		current := &pointShape{position: origin.Add(direction.Multiply(t))}
+		_, closest, intersecting := gjk(current, shape)
+		if intersecting {
+			// Inside from the start, or the previous step landed on the boundary
+			if t == 0 {
+				return 0, vector.Vec2{}, false
+			}
+			return t, normal, true
+		}
+
+		distance := closest.Magnitude()
+		normal = closest.Multiply(1 / distance)
+		if distance < constants.GJK_TOLERANCE {
+			return t, normal, true
+		}
+
+		// How fast the ray gets closer to the shape, the normal points out of it
+		approach := -direction.Dot(normal)
+		if approach <= 0 {
+			return 0, vector.Vec2{}, false
+		}
+
+		t += distance / approach
+		if t > maxDistance {
+			return 0, vector.Vec2{}, false
+		}
+	}
+
+	return 0, vector.Vec2{}, false
+}
@@ -0,0 +1,108 @@
+package collision
+
+import (
+	"engine/entities"
+	"reflect"
+)
+
+/*
+Narrowphase routine for one pair of shape types. It gets the shapes in the
+order they were registered, already in world space (see
+Shape.UpdateVertices), and returns nil if they are not touching. The normal
+must point from shapeA to shapeB, Detect fills in the bodies.
+*/
+type DetectFunc func(shapeA entities.Shape, shapeB entities.Shape) *Collision
+
+type shapePair struct {
+	a reflect.Type
+	b reflect.Type
+}
+
+var registry = map[shapePair]DetectFunc{}
+
+/*
+Registers the narrowphase for the shapes of type shapeA and shapeB, the values
+themselves are only used for their type:
+
+	collision.Register(&Ellipse{}, &entities.Polygon{}, detectEllipsePolygon)
+
+The opposite order doesn't need its own function, (B, A) calls detect with the
+shapes swapped and flips the result (see Collision.flip). A pair without
+routine falls back to GJK/EPA.
+*/
+func Register(shapeA entities.Shape, shapeB entities.Shape, detect DetectFunc) {
+	registry[shapePair{a: reflect.TypeOf(shapeA), b: reflect.TypeOf(shapeB)}] = detect
+}
+
+func lookup(shapeA entities.Shape, shapeB entities.Shape) (DetectFunc, bool) {
+	typeA := reflect.TypeOf(shapeA)
+	typeB := reflect.TypeOf(shapeB)
+
+	if detect, ok := registry[shapePair{a: typeA, b: typeB}]; ok {
+		return detect, true
+	}
+
+	detect, ok := registry[shapePair{a: typeB, b: typeA}]
+	if !ok {
+		return nil, false
+	}
+
+	mirrored := func(shapeA entities.Shape, shapeB entities.Shape) *Collision {
+		collision := detect(shapeB, shapeA)
+		if collision != nil {
+			collision.flip()
+		}
+		return collision
+	}
+	return mirrored, true
+}
+
+/*
+Swaps A and B. The normal has to point the other way and the contact points
+swap roles: the point of B inside of A becomes the point of A inside of B.
+*/
+func (collision *Collision) flip() {
+	collision.BodyA, collision.BodyB = collision.BodyB, collision.BodyA
+	collision.FixtureA, collision.FixtureB = collision.FixtureB, collision.FixtureA
+	collision.Normal = collision.Normal.Multiply(-1)
+
+	for i := range collision.Contacts {
+		contact := &collision.Contacts[i]
+		contact.Start, contact.End = contact.End, contact.Start
+	}
+}
+
+// The shapes that come with the engine have their own routines, they are faster than GJK/EPA.
+func init() {
+	Register(&entities.Circle{}, &entities.Circle{}, func(shapeA entities.Shape, shapeB entities.Shape) *Collision {
+		return calculateCirCleCirCleCollission(shapeA.(*entities.Circle), shapeB.(*entities.Circle))
+	})
+
+	Register(&entities.Polygon{}, &entities.Polygon{}, func(shapeA entities.Shape, shapeB entities.Shape) *Collision {
+		return calculatePolygonPolygonCollision(shapeA.(*entities.Polygon), shapeB.(*entities.Polygon))
+	})
+
+	Register(&entities.Polygon{}, &entities.Circle{}, func(shapeA entities.Shape, shapeB entities.Shape) *Collision {
+		return calculatePolygonCircleCollision(shapeA.(*entities.Polygon), shapeB.(*entities.Circle))
+	})
+
+	Register(&entities.Capsule{}, &entities.Circle{}, func(shapeA entities.Shape, shapeB entities.Shape) *Collision {
+		return calculateCapsuleCircleCollision(shapeA.(*entities.Capsule), shapeB.(*entities.Circle))
+	})
+
+	Register(&entities.Capsule{}, &entities.Capsule{}, func(shapeA entities.Shape, shapeB entities.Shape) *Collision {
+		return calculateCapsuleCapsuleCollision(shapeA.(*entities.Capsule), shapeB.(*entities.Capsule))
+	})
+
+	Register(&entities.Polygon{}, &entities.Capsule{}, func(shapeA entities.Shape, shapeB entities.Shape) *Collision {
+		return calculatePolygonCapsuleCollision(shapeA.(*entities.Polygon), shapeB.(*entities.Capsule))
+	})
+
+	Register(&entities.Segment{}, &entities.Circle{}, func(shapeA entities.Shape, shapeB entities.Shape) *Collision {
+		return calculateSegmentCircleCollision(shapeA.(*entities.Segment), shapeB.(*entities.Circle))
+	})
+
+	Register(&entities.Segment{}, &entities.Polygon{}, func(shapeA entities.Shape, shapeB entities.Shape) *Collision {
+		return calculateSegmentPolygonCollision(shapeA.(*entities.Segment), shapeB.(*entities.Polygon))
+	})
+}
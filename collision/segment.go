@@ -0,0 +1,137 @@
+package collision
+
+import (
+	"engine/constants"
+	"engine/entities"
+	"engine/vector"
+)
+
+/*
+The circle touches the inside of the segment, or one of its ends. Ends with
+a ghost vertex are shared with the next segment of the chain: if the circle
+is over that segment the contact belongs to it, otherwise both segments
+would push the circle and it would bump on the joint. The joint itself
+belongs to the segment that ends there, the one that starts there skips it
+or the circle would get the contact twice.
+
+	ghostA    A            B    ghostB
+	  o-------o============o-------o
+	  region  |  region AB |  region
+	  of A    |            |  of B
+*/
+func calculateSegmentCircleCollision(segment *entities.Segment, circle *entities.Circle) *Collision {
+	ab := segment.B.Subtract(segment.A)
+	ap := circle.Center.Subtract(segment.A)
+	bp := circle.Center.Subtract(segment.B)
+
+	// Where the center projects on the segment: before A, after B or in between
+	if ap.Dot(ab) <= 0 && segment.HasGhostA {
+		// Over the previous segment or its end, either way that one reports it
+		return nil
+	}
+	if bp.Dot(ab) >= 0 && segment.HasGhostB {
+		next := segment.GhostB.Subtract(segment.B)
+		if next.Dot(bp) > 0 {
+			return nil
+		}
+	}
+
+	closest := closestPointOnSegment(circle.Center, segment.A, segment.B)
+	return roundCollision(closest, 0, circle.Center, float64(circle.Radius), ab)
+}
+
+/*
+The segment is a polygon with two vertices, each of its edges is one of its
+sides, so it goes through the same SAT and clipping as two polygons (see
+calculatePolygonPolygonCollision).
+
+The catch is the reference: when an edge of the polygon wins the normal is
+the one of that edge. Right over a joint of a chain the end of the segment
+is barely inside of the polygon through its side, the normal would point
+sideways and the body would stop there. So a normal of the polygon is only
+used when the terrain really has a corner there (see admissibleNormal),
+otherwise the normal of the segment is.
+*/
+func calculateSegmentPolygonCollision(segment *entities.Segment, polygon *entities.Polygon) *Collision {
+	edge := &entities.Polygon{WorldVertices: []vector.Vec2{segment.A, segment.B}}
+
+	penetrationSegment, side := calculatePenetration(edge, polygon)
+	penetrationPolygon, polygonEdge := calculatePenetration(polygon, edge)
+	if penetrationSegment > constants.CONTACT_MARGIN || penetrationPolygon > constants.CONTACT_MARGIN {
+		return nil
+	}
+
+	// Same tolerances as two polygons, the segment is preferred
+	const relativeTolerance = 0.98
+	const absoluteTolerance = 0.05 // pix
+	if penetrationPolygon > relativeTolerance*penetrationSegment+absoluteTolerance {
+		polygonNormal := polygon.EdgeAt(polygonEdge)
+		polygonNormal = polygonNormal.Normal()
+		normal := polygonNormal.Multiply(-1) // from the segment to the polygon
+
+		if admissibleNormal(segment, edge, side, polygon.WorldVertices[polygonEdge], polygonNormal, normal) {
+			contacts := clipContacts(polygon, edge, polygonEdge, true)
+			if len(contacts) == 0 {
+				return nil
+			}
+			return &Collision{Normal: normal, Contacts: contacts}
+		}
+	}
+
+	normal := edge.EdgeAt(side)
+	normal = normal.Normal()
+	contacts := clipContacts(edge, polygon, side, false)
+	if len(contacts) == 0 {
+		return nil
+	}
+	return &Collision{Normal: normal, Contacts: contacts}
+}
+
+/*
+Checks that normal can come out of the end of the segment that is inside of
+the polygon. Without a ghost vertex anything goes. With one, the terrain
+only has a corner there if the next segment bends away from the side the
+polygon is on (convex):
+
+	       normal
+	   n     ^    n2
+	    ^    |   ^
+	    |   /   /
+	====A---   ghost
+	         \
+	          \
+
+and then the normal has to be between the normals of both segments. Flat and
+concave joints have no corner, only the normal of the segment works.
+*/
+func admissibleNormal(segment *entities.Segment, edge *entities.Polygon, side int, polygonVertex vector.Vec2, polygonNormal vector.Vec2, normal vector.Vec2) bool {
+	// End of the segment that is the deepest inside of the polygon through that edge
+	end, ghost, hasGhost := segment.A, segment.GhostA, segment.HasGhostA
+	da := segment.A.Subtract(polygonVertex)
+	db := segment.B.Subtract(polygonVertex)
+	if db.Dot(polygonNormal) < da.Dot(polygonNormal) {
+		end, ghost, hasGhost = segment.B, segment.GhostB, segment.HasGhostB
+	}
+
+	if !hasGhost {
+		return true
+	}
+
+	// Normal of the side of the segment, and of the next segment, that face the polygon
+	n := edge.EdgeAt(side)
+	n = n.Normal()
+	toGhost := ghost.Subtract(end)
+	n2 := toGhost.Normal()
+	if n2.Dot(n) < 0 || (n2.Dot(n) == 0 && toGhost.Dot(segment.B.Subtract(segment.A)) == 0) {
+		n2 = n2.Multiply(-1)
+	}
+
+	const tolerance = 0.01
+	convex := toGhost.Dot(n) < -tolerance*toGhost.Magnitude()
+	if !convex {
+		return false
+	}
+
+	spread := n.Dot(n2)
+	return normal.Dot(n) >= spread-tolerance && normal.Dot(n2) >= spread-tolerance
+}
@@ -0,0 +1,49 @@
+package collision
+
+import (
+	"engine/entities"
+	"engine/renderer"
+	"engine/vector"
+	"math"
+	"testing"
+)
+
+func chainBody(vertices []vector.Vec2) *entities.Body {
+	chain := entities.NewChain(renderer.WHITE, vertices, false)
+	chain.UpdateVertices(vector.Vec2{}, 0)
+	return &entities.Body{Shape: chain, Type: entities.BODY_STATIC}
+}
+
+func circleBody(center vector.Vec2, radius int32) *entities.Body {
+	circle := entities.CircleShape(radius, renderer.WHITE)
+	circle.UpdateVertices(center, 0)
+	return &entities.Body{Shape: circle, Type: entities.BODY_DYNAMIC}
+}
+
+// Only one segment gets the contact of a circle resting on a joint of the chain.
+func TestSegmentCircleJointContact(t *testing.T) {
+	cases := []struct {
+		name     string
+		vertices []vector.Vec2
+	}{
+		{"convex joint", []vector.Vec2{{X: -50, Y: 20}, {X: 0, Y: 0}, {X: 50, Y: 20}}},
+		{"flat joint", []vector.Vec2{{X: -50, Y: 0}, {X: 0, Y: 0}, {X: 50, Y: 0}}},
+	}
+
+	for _, c := range cases {
+		// 1 pix into the joint
+		collisions := Detect(chainBody(c.vertices), circleBody(vector.Vec2{X: 0, Y: -9}, 10))
+		contacts := 0
+		for _, collision := range collisions {
+			for _, contact := range collision.Contacts {
+				contacts++
+				if math.Abs(contact.Depth-1) > 1e-9 {
+					t.Errorf("%s: depth %.3f, want 1", c.name, contact.Depth)
+				}
+			}
+		}
+		if contacts != 1 {
+			t.Errorf("%s: %d contacts, want 1", c.name, contacts)
+		}
+	}
+}
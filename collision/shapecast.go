@@ -0,0 +1,99 @@
+package collision
+
+import (
+	"engine/constants"
+	"engine/entities"
+	"engine/vector"
+)
+
+/*
+Where a shape moved along a translation first touches a body. Fraction is
+how much of the translation it can move before touching, the shape is then
+at start + translation * Fraction.
+*/
+type ShapeCastHit struct {
+	Body     *entities.Body
+	Point    vector.Vec2
+	Normal   vector.Vec2 // of the surface of the body, it points towards the shape
+	Fraction float64
+}
+
+/*
+Sweeps shape from start along translation (without rotating it) against the
+shape of the body, the same way rays are cast against arbitrary shapes (see
+rayCastConvex): GJK gives the gap and the normal between both shapes, nothing
+can touch before the shape covers that gap along the normal, so it jumps that
+far and tries again.
+
+A shape that already overlaps the body hits it at Fraction 0. Compound
+bodies are hit by their first fixture in the way. The shape is left at
+start + translation * Fraction, or back at start when nothing is hit.
+*/
+func ShapeCast(shape entities.Shape, start entities.Transform, translation vector.Vec2, body *entities.Body) (ShapeCastHit, bool) {
+	fraction := 1.0
+	var normal vector.Vec2
+	hit := false
+	for _, fixture := range body.Fixtures() {
+		if t, n, ok := shapeCast(shape, start, translation, fixture.Shape); ok && t <= fraction {
+			fraction, normal, hit = t, n, true
+		}
+	}
+
+	if !hit {
+		// shapeCast leaves it wherever it gave up
+		shape.UpdateVertices(start.Position, start.Rotation)
+		return ShapeCastHit{}, false
+	}
+
+	shape.UpdateVertices(start.Position.Add(translation.Multiply(fraction)), start.Rotation)
+	return shapeCastHit(shape, body, normal, fraction), true
+}
+
+// Fraction of the translation shape can move before touching target, and the normal of target there.
+func shapeCast(shape entities.Shape, start entities.Transform, translation vector.Vec2, target entities.Shape) (float64, vector.Vec2, bool) {
+	t := 0.0
+	var normal vector.Vec2
+
+	for i := 0; i < constants.GJK_ITERATIONS; i++ {
+		position := start.Position.Add(translation.Multiply(t))
+		shape.UpdateVertices(position, start.Rotation)
+
+		simplex, closest, intersecting := gjk(shape, target)
+		if intersecting {
+			if t == 0 {
+				// epa gives the normal from the shape to the target
+				normal, _ = epa(shape, target, simplex)
+				normal = normal.Multiply(-1)
+			}
+			return t, normal, true
+		}
+
+		distance := closest.Magnitude()
+		normal = closest.Multiply(1 / distance)
+		if distance < constants.GJK_TOLERANCE {
+			return t, normal, true
+		}
+
+		// How fast the shape gets closer to the target along the normal
+		approach := -translation.Dot(normal)
+		if approach <= 0 {
+			return 0, vector.Vec2{}, false
+		}
+
+		t += distance / approach
+		if t > 1 {
+			return 0, vector.Vec2{}, false
+		}
+	}
+
+	return 0, vector.Vec2{}, false
+}
+
+func shapeCastHit(shape entities.Shape, body *entities.Body, normal vector.Vec2, t float64) ShapeCastHit {
+	return ShapeCastHit{
+		Body:     body,
+		Point:    shape.Support(normal.Multiply(-1)),
+		Normal:   normal,
+		Fraction: t,
+	}
+}
@@ -0,0 +1,35 @@
+package collision
+
+import (
+	"engine/entities"
+	"engine/renderer"
+	"engine/vector"
+	"testing"
+)
+
+func TestShapeCastMissKeepsStart(t *testing.T) {
+	cases := []struct {
+		name        string
+		translation vector.Vec2
+	}{
+		{"moving away", vector.Vec2{X: -50, Y: 0}},
+		{"stopping short", vector.Vec2{X: 50, Y: 0}},
+		{"passing by", vector.Vec2{X: 100, Y: 100}},
+	}
+
+	for _, c := range cases {
+		target := boxBody(20, 20, vector.Vec2{X: 100, Y: 0}, 0)
+		shape := entities.NewBox(renderer.WHITE, 20, 20)
+		start := entities.Transform{Position: vector.Vec2{X: 0, Y: 0}, Rotation: 0.3}
+		shape.UpdateVertices(start.Position, start.Rotation)
+		want := shape.GetAABB()
+
+		if hit, ok := ShapeCast(shape, start, c.translation, target); ok {
+			t.Errorf("%s: hit at fraction %.3f, want a miss", c.name, hit.Fraction)
+			continue
+		}
+		if got := shape.GetAABB(); got != want {
+			t.Errorf("%s: shape moved to %v, want it back at %v", c.name, got, want)
+		}
+	}
+}
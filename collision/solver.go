@@ -0,0 +1,254 @@
+package collision
+
+import (
+	"engine/constants"
+	"engine/entities"
+	"engine/vector"
+	"math"
+)
+
+/*
+Sequential impulse solver. Instead of fixing every pair as soon as it is found,
+all the collisions of the step are gathered first and then the impulses are
+applied over and over (Iterations times). Every pass sees the velocities left by
+the previous one, so the pairs of a stack end up agreeing on a solution no
+matter the order in which the bodies were added.
+*/
+type Solver struct {
+	Iterations         int
+	PositionIterations int // only used by CORRECTION_NGS
+	// Start from the impulses of the previous step (see Collision.WarmStart)
+	WarmStarting bool
+
+	// How the penetration is removed (see PositionCorrection)
+	Correction PositionCorrection
+	Beta       float64 // % of the depth fixed per step
+	Slop       float64 // pix of overlap that is never corrected
+
+	// State of the last step, needed to correct the positions once the bodies moved
+	collisions       []*Collision
+	pseudoVelocities map[*entities.Body]*pseudoVelocity
+	dt               float64
+}
+
+func NewSolver(iterations int) *Solver {
+	return &Solver{
+		Iterations:         iterations,
+		PositionIterations: constants.POSITION_ITERATIONS,
+		WarmStarting:       true,
+		Correction:         CORRECTION_BAUMGARTE,
+		Beta:               constants.BAUMGARTE_BETA,
+		Slop:               constants.PENETRATION_SLOP,
+	}
+}
+
+/*
+Solves the velocities of the step. It has to be called before the velocities
+are integrated, and CorrectPositions right after.
+*/
+func (solver *Solver) Solve(collisions []*Collision, dt float64) {
+	solver.collisions = collisions
+	solver.dt = dt
+
+	for _, collision := range collisions {
+		solver.prepareImpulse(collision, dt)
+	}
+
+	if solver.WarmStarting {
+		for _, collision := range collisions {
+			warmStart(collision)
+		}
+	}
+
+	for i := 0; i < solver.Iterations; i++ {
+		for _, collision := range collisions {
+			resolveImpulse(collision)
+		}
+	}
+
+	if solver.Correction == CORRECTION_SPLIT_IMPULSE {
+		solver.solvePseudoVelocities()
+	}
+}
+
+// Calculates everything that does not change between iterations.
+func (solver *Solver) prepareImpulse(collision *Collision, dt float64) {
+	bodyA := collision.BodyA
+	bodyB := collision.BodyB
+
+	normal := collision.Normal
+	tangent := normal.Normal()
+	e := collision.Restitution
+	staticFriction := collision.StaticFriction
+	dynamicFriction := collision.DynamicFriction
+
+	for i := range collision.Contacts {
+		contact := &collision.Contacts[i]
+
+		// r is the distance from the center of mass to the point of collision aprox.
+		contact.ra = contact.End.Subtract(bodyA.Position)
+		contact.rb = contact.Start.Subtract(bodyB.Position)
+
+		/*
+		                         1
+		   mass = ---------------------------------------
+		            1      1     (ra X dir)^2   (rb x dir)^2
+		           ---- + ---- + ------------ + ------------
+		            Ma     Mb         Ia             Ib
+		*/
+		contact.normalMass = effectiveMass(bodyA, bodyB, contact.ra, contact.rb, normal)
+		contact.tangentMass = effectiveMass(bodyA, bodyB, contact.ra, contact.rb, tangent)
+
+		// Restitution is a target velocity along the normal, computed once with the velocity before solving
+		contact.velocityBias = 0
+		vRel := relativeVelocity(bodyA, bodyB, contact)
+		vRelNormal := vRel.Dot(normal)
+		if vRelNormal < -constants.RESTITUTION_THRESHOLD {
+			contact.velocityBias = -e * vRelNormal
+		}
+
+		// Surfaces that are already sliding only get the dynamic friction
+		contact.friction = staticFriction
+		if math.Abs(vRel.Dot(tangent)+collision.TangentSpeed) > constants.STATIC_FRICTION_SPEED {
+			contact.friction = dynamicFriction
+		}
+
+		/*
+			With Baumgarte the penetration is fixed through the velocity as well, the
+			bodies get an extra separating velocity proportional to the depth (see
+			constrains_theory/ground-constraint.js). The other strategies keep it out
+			of the real velocity (see CorrectPositions).
+		*/
+		penetration := math.Max(contact.Depth-solver.Slop, 0)
+		contact.positionBias = solver.Beta / dt * penetration
+		contact.positionImpulse = 0
+		if solver.Correction == CORRECTION_BAUMGARTE {
+			contact.velocityBias += contact.positionBias
+		}
+		if solver.Correction == CORRECTION_NGS {
+			contact.localA = toLocal(bodyA, contact.End)
+			contact.localB = toLocal(bodyB, contact.Start)
+			contact.localNormal = normal.Rotate(-bodyA.Rotation)
+		}
+
+		/*
+			Contacts that are not touching yet (see CONTACT_MARGIN) let the bodies close
+			the gap in one step. Fast bodies bounce straight away instead, the gap is
+			tiny and otherwise a bullet stopped right before a wall would lose its bounce.
+		*/
+		if contact.Depth < 0 && contact.velocityBias == 0 {
+			contact.velocityBias = contact.Depth / dt
+		}
+
+		if !solver.WarmStarting {
+			contact.NormalImpulse = 0
+			contact.TangentImpulse = 0
+		}
+	}
+}
+
+/*
+Applies what the contacts ended up with last step straight away. It runs once
+all the collisions are prepared, the restitution has to be computed with the
+velocities before any impulse.
+*/
+func warmStart(collision *Collision) {
+	normal := collision.Normal
+	tangent := normal.Normal()
+
+	for i := range collision.Contacts {
+		contact := &collision.Contacts[i]
+
+		J := normal.Multiply(contact.NormalImpulse)
+		J = J.Add(tangent.Multiply(contact.TangentImpulse))
+		applyImpulses(collision.BodyA, collision.BodyB, contact, J)
+	}
+}
+
+/*
+One pass over the contacts of a collision. The impulses are accumulated over
+the iterations and it is the total that gets clamped, not each increment:
+
+  - Along the normal the total can't be negative (contacts push, never pull).
+  - Along the tangent the total has to stay inside the friction cone,
+    |Jt| <= mu * Jn (Coulomb friction).
+
+Clamping the total allows an iteration to take back part of what a previous
+one applied in excess.
+
+mu is the static coefficient while the surfaces are at rest. If holding them
+in place needs more than that they break loose, and from the next step on
+they slide with the dynamic coefficient (see prepareImpulse).
+*/
+func resolveImpulse(collision *Collision) {
+	bodyA := collision.BodyA
+	bodyB := collision.BodyB
+
+	normal := collision.Normal
+	tangent := normal.Normal()
+
+	for i := range collision.Contacts {
+		contact := &collision.Contacts[i]
+
+		// Friction first, limited by the normal impulse of the previous iteration. It
+		// stops the surfaces from sliding, not the bodies (see Body.SurfaceVelocity)
+		vRel := relativeVelocity(bodyA, bodyB, contact)
+		jt := -contact.tangentMass * (vRel.Dot(tangent) + collision.TangentSpeed)
+
+		maxFriction := contact.friction * contact.NormalImpulse
+		oldTangentImpulse := contact.TangentImpulse
+		contact.TangentImpulse = math.Max(-maxFriction, math.Min(oldTangentImpulse+jt, maxFriction))
+		jt = contact.TangentImpulse - oldTangentImpulse
+
+		applyImpulses(bodyA, bodyB, contact, tangent.Multiply(jt))
+
+		vRel = relativeVelocity(bodyA, bodyB, contact)
+		jn := -contact.normalMass * (vRel.Dot(normal) - contact.velocityBias)
+
+		oldNormalImpulse := contact.NormalImpulse
+		contact.NormalImpulse = math.Max(oldNormalImpulse+jn, 0)
+		jn = contact.NormalImpulse - oldNormalImpulse
+
+		applyImpulses(bodyA, bodyB, contact, normal.Multiply(jn))
+	}
+}
+
+// Velocity of B relative to A at the contact point: vRel = (vb + wb X rb) - (va + wa X ra)
+func relativeVelocity(bodyA *entities.Body, bodyB *entities.Body, contact *Contact) vector.Vec2 {
+	Va := bodyA.Velocity.Add(bodyA.AngularVelocityProduct(contact.ra))
+	Vb := bodyB.Velocity.Add(bodyB.AngularVelocityProduct(contact.rb))
+	return Vb.Subtract(Va)
+}
+
+// J pushes B along its direction and A the opposite way.
+func applyImpulses(bodyA *entities.Body, bodyB *entities.Body, contact *Contact, J vector.Vec2) {
+	if bodyA.IsDynamic() {
+		bodyA.ApplyImpulse(J.Multiply(-1), contact.ra)
+	}
+
+	if bodyB.IsDynamic() {
+		bodyB.ApplyImpulse(J, contact.rb)
+	}
+}
+
+func effectiveMass(bodyA *entities.Body, bodyB *entities.Body, ra vector.Vec2, rb vector.Vec2, direction vector.Vec2) float64 {
+	invMassA, invInertiaA := inverseMass(bodyA)
+	invMassB, invInertiaB := inverseMass(bodyB)
+
+	raCrossDir := ra.Cross(direction)
+	rbCrossDir := rb.Cross(direction)
+
+	k := invMassA + invMassB + raCrossDir*raCrossDir*invInertiaA + rbCrossDir*rbCrossDir*invInertiaB
+	if k == 0 {
+		return 0
+	}
+	return 1 / k
+}
+
+// Static and kinematic bodies behave as if they had infinite mass
+func inverseMass(body *entities.Body) (float64, float64) {
+	if !body.IsDynamic() {
+		return 0, 0
+	}
+	return body.InvMass, body.InvInertia
+}
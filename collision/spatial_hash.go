@@ -0,0 +1,135 @@
+package collision
+
+import (
+	"engine/constants"
+	"engine/entities"
+	"engine/vector"
+	"math"
+)
+
+/*
+Uniform grid of square cells. Every body is inserted in all the cells its
+bounding box touches and only bodies sharing a cell become candidate pairs.
+
+Works best when the cell size is close to the size of the typical body, big
+static bodies (floors, walls) span many cells but that is still cheap.
+The grid is rebuilt from scratch on every Update.
+*/
+type SpatialHash struct {
+	CellSize float64
+
+	cells  map[cellKey][]int
+	bodies []*entities.Body
+	aabbs  []entities.AABB
+	bounds entities.AABB // of all the bodies, nothing is outside of it
+}
+
+type cellKey struct {
+	x int
+	y int
+}
+
+func NewSpatialHash(cellSize float64) *SpatialHash {
+	return &SpatialHash{
+		CellSize: cellSize,
+		cells:    map[cellKey][]int{},
+	}
+}
+
+func (hash *SpatialHash) Update(bodies []*entities.Body) {
+	clear(hash.cells)
+	hash.bodies = bodies
+	hash.aabbs = hash.aabbs[:0]
+
+	for idx, body := range bodies {
+		aabb := body.GetAABB()
+		// Shapes closer than the contact margin still produce contacts
+		aabb = aabb.Expand(constants.CONTACT_MARGIN)
+		hash.aabbs = append(hash.aabbs, aabb)
+		if idx == 0 {
+			hash.bounds = aabb
+		} else {
+			hash.bounds = hash.bounds.Union(aabb)
+		}
+
+		minX, minY := hash.cellCoordinates(aabb.Min.X, aabb.Min.Y)
+		maxX, maxY := hash.cellCoordinates(aabb.Max.X, aabb.Max.Y)
+
+		for x := minX; x <= maxX; x++ {
+			for y := minY; y <= maxY; y++ {
+				key := cellKey{x: x, y: y}
+				hash.cells[key] = append(hash.cells[key], idx)
+			}
+		}
+	}
+}
+
+func (hash *SpatialHash) Pairs() []Pair {
+	// Two bodies can share more than one cell so the pairs need to be deduplicated.
+	found := map[indexPair]bool{}
+
+	for _, cell := range hash.cells {
+		for i := 0; i < len(cell)-1; i++ {
+			for j := i + 1; j < len(cell); j++ {
+				key := newIndexPair(cell[i], cell[j])
+				if found[key] {
+					continue
+				}
+
+				if hash.aabbs[key.a].Overlaps(hash.aabbs[key.b]) {
+					found[key] = true
+				}
+			}
+		}
+	}
+
+	return sortedPairs(found, hash.bodies)
+}
+
+/*
+Only the cells under both aabb and the bodies are walked, a long ray (or one
+that never ends) would go through more cells than there are bodies. If that
+is still too many cells the bodies are tested one by one instead.
+*/
+func (hash *SpatialHash) Query(aabb entities.AABB, callback func(body *entities.Body) bool) {
+	if len(hash.aabbs) == 0 || !hash.bounds.Overlaps(aabb) {
+		return
+	}
+
+	clamped := entities.AABB{
+		Min: vector.Vec2{X: math.Max(aabb.Min.X, hash.bounds.Min.X), Y: math.Max(aabb.Min.Y, hash.bounds.Min.Y)},
+		Max: vector.Vec2{X: math.Min(aabb.Max.X, hash.bounds.Max.X), Y: math.Min(aabb.Max.Y, hash.bounds.Max.Y)},
+	}
+	minX, minY := hash.cellCoordinates(clamped.Min.X, clamped.Min.Y)
+	maxX, maxY := hash.cellCoordinates(clamped.Max.X, clamped.Max.Y)
+
+	if (maxX-minX+1)*(maxY-minY+1) > len(hash.aabbs) {
+		for idx, bodyAABB := range hash.aabbs {
+			if bodyAABB.Overlaps(aabb) && !callback(hash.bodies[idx]) {
+				return
+			}
+		}
+		return
+	}
+
+	// Big bodies are in many cells, each of them is reported once
+	visited := map[int]bool{}
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			for _, idx := range hash.cells[cellKey{x: x, y: y}] {
+				if visited[idx] || !hash.aabbs[idx].Overlaps(aabb) {
+					continue
+				}
+				visited[idx] = true
+
+				if !callback(hash.bodies[idx]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (hash *SpatialHash) cellCoordinates(x float64, y float64) (int, int) {
+	return int(math.Floor(x / hash.CellSize)), int(math.Floor(y / hash.CellSize))
+}
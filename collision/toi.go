@@ -0,0 +1,83 @@
+package collision
+
+import (
+	"engine/constants"
+	"engine/entities"
+	"engine/vector"
+	"math"
+)
+
+/*
+Continuous collision detection. Collisions are only checked where the bodies
+are at the end of the step, so a body moving more than its own size per step
+can jump over a thin wall without ever overlapping it (tunneling).
+
+The motion of the step is sampled from t = 0 to t = 1 in increments small
+enough that the body can't skip the wall in between:
+
+	position(t) = position + velocity * dt * t
+	rotation(t) = rotation + angularVelocity * dt * t
+
+and the first overlapping sample is refined with a bisection. Returns the
+fraction of the step where moving first touches target, or 1 and false if it
+doesn't. Bodies already touching at t = 0 are left to the regular contacts.
+*/
+func TimeOfImpact(moving *entities.Body, target *entities.Body, dt float64) (float64, bool) {
+	position := moving.Position
+	rotation := moving.Rotation
+	defer setTransform(moving, position, rotation)
+
+	translation := moving.Velocity.Multiply(dt)
+	angle := moving.AngularVelocity * dt
+
+	/*
+		How far the body goes in the step. The rotation is bounded by the
+		distance travelled by the farthest point of the shape.
+	*/
+	aabb := moving.GetAABB()
+	extent := aabb.Max.Subtract(aabb.Min)
+	radius := extent.Magnitude() / 2
+	distance := translation.Magnitude() + math.Abs(angle)*radius
+
+	// No sample can move more than half of the thinnest side of the body
+	sampleSize := math.Max(math.Min(extent.X, extent.Y)/2, constants.CONTACT_MARGIN)
+	samples := int(math.Ceil(distance / sampleSize))
+
+	at := func(t float64) bool {
+		setTransform(moving, position.Add(translation.Multiply(t)), rotation+angle*t)
+		return TestOverlap(moving, target)
+	}
+
+	if samples == 0 || at(0) {
+		return 1, false
+	}
+
+	before := 0.0
+	for i := 1; i <= samples; i++ {
+		t := float64(i) / float64(samples)
+		if !at(t) {
+			before = t
+			continue
+		}
+
+		// The impact is somewhere between before and t
+		after := t
+		for j := 0; j < constants.TOI_ITERATIONS; j++ {
+			middle := (before + after) / 2
+			if at(middle) {
+				after = middle
+			} else {
+				before = middle
+			}
+		}
+		return before, true
+	}
+
+	return 1, false
+}
+
+func setTransform(body *entities.Body, position vector.Vec2, rotation float64) {
+	body.Position = position
+	body.Rotation = rotation
+	body.Shape.UpdateVertices(position, rotation)
+}
@@ -9,4 +9,18 @@ const (
 	SPRING_REST_LENGTH      float64 = 15
 	SPRING_CONSTANT         float64 = 300
 	SPRING_SIZE             uint64  = 10
+	AABB_MARGIN             float64 = 5  // pix, how much the dynamic tree boxes are inflated
+	SPATIAL_HASH_CELL_SIZE  float64 = 64 // pix
+	VELOCITY_ITERATIONS     int     = 10
+	POSITION_ITERATIONS     int     = 3
+	GJK_ITERATIONS          int     = 32
+	GJK_TOLERANCE           float64 = 0.01                // pix
+	TOI_ITERATIONS          int     = 10                  // bisection steps used to refine the time of impact
+	RESTITUTION_THRESHOLD   float64 = 1 * PIXEL_PER_METER // pix/s, slower impacts don't bounce
+	CONTACT_MARGIN          float64 = 1                   // pix, points this close are kept as contacts
+	PENETRATION_SLOP        float64 = 0.5                 // pix of overlap that is never corrected
+	BAUMGARTE_BETA          float64 = 0.2                 // % of the depth fixed per step
+	MAX_POSITION_CORRECTION float64 = 8                   // pix, max correction per position iteration
+	STATIC_FRICTION_SPEED   float64 = 2.5                 // pix/s, slower surfaces are considered at rest
+	DEFAULT_DENSITY         float64 = 0.0004              // kg/pix^2, 1 kg/m^2 (a 50x50 box weighs 1 kg)
 )
@@ -0,0 +1,62 @@
+package entities
+
+import (
+	"engine/vector"
+	"math"
+)
+
+// Axis aligned bounding box in world coordinates.
+type AABB struct {
+	Min vector.Vec2
+	Max vector.Vec2
+}
+
+func (aabb *AABB) Overlaps(other AABB) bool {
+	return aabb.Min.X <= other.Max.X && aabb.Max.X >= other.Min.X &&
+		aabb.Min.Y <= other.Max.Y && aabb.Max.Y >= other.Min.Y
+}
+
+// True if other lies completely inside of aabb.
+func (aabb *AABB) Contains(other AABB) bool {
+	return aabb.Min.X <= other.Min.X && aabb.Min.Y <= other.Min.Y &&
+		aabb.Max.X >= other.Max.X && aabb.Max.Y >= other.Max.Y
+}
+
+func (aabb *AABB) Union(other AABB) AABB {
+	return AABB{
+		Min: vector.Vec2{X: math.Min(aabb.Min.X, other.Min.X), Y: math.Min(aabb.Min.Y, other.Min.Y)},
+		Max: vector.Vec2{X: math.Max(aabb.Max.X, other.Max.X), Y: math.Max(aabb.Max.Y, other.Max.Y)},
+	}
+}
+
+// Grows the box by margin in every direction.
+func (aabb *AABB) Expand(margin float64) AABB {
+	return AABB{
+		Min: vector.Vec2{X: aabb.Min.X - margin, Y: aabb.Min.Y - margin},
+		Max: vector.Vec2{X: aabb.Max.X + margin, Y: aabb.Max.Y + margin},
+	}
+}
+
+/*
+Used as the cost of a node in the dynamic tree. The perimeter is preferred over
+the area because it behaves better for thin boxes (walls, floors).
+*/
+func (aabb *AABB) Perimeter() float64 {
+	return 2 * ((aabb.Max.X - aabb.Min.X) + (aabb.Max.Y - aabb.Min.Y))
+}
+
+func AABBFromVertices(vertices []vector.Vec2) AABB {
+	aabb := AABB{
+		Min: vector.Vec2{X: math.Inf(1), Y: math.Inf(1)},
+		Max: vector.Vec2{X: math.Inf(-1), Y: math.Inf(-1)},
+	}
+
+	for _, vertex := range vertices {
+		aabb.Min.X = math.Min(aabb.Min.X, vertex.X)
+		aabb.Min.Y = math.Min(aabb.Min.Y, vertex.Y)
+		aabb.Max.X = math.Max(aabb.Max.X, vertex.X)
+		aabb.Max.Y = math.Max(aabb.Max.Y, vertex.Y)
+	}
+
+	return aabb
+}
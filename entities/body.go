@@ -3,16 +3,31 @@ package entities
 import (
 	"engine/renderer"
 	"engine/vector"
+	"sync/atomic"
 )
 
 type Body struct {
-	Static  bool
+	id      uint64 // see ID
+	Type    BodyType
+	Bullet  bool // fast body, swept against the static bodies so it can't go through them (see collision.TimeOfImpact)
+	Sensor  bool // only reports the bodies that overlap it, nothing collides with it (see game.SensorListener)
 	Name    string
 	Texture *renderer.SDLTexture
+	Path    *Path // scripted movement of a kinematic body, nil if the game moves it (see Path)
+
+	/*
+		Mass properties, they change together through SetDensity, SetMass and
+		ResetMassData. The solver only reads the inverses, so after writing Mass
+		or Density by hand (or changing the Shape or the Type) call ResetMassData.
+		World.AddBody does it for dynamic bodies that have no InvMass yet.
+	*/
+	Density    float64 // mass per pix^2 of the shape
+	Mass       float64
+	InvMass    float64
+	Inertia    float64 // moment of inertia, the shape's one times the mass
+	InvInertia float64
 
 	// Linear properties
-	Mass         float64
-	InvMass      float64
 	Position     vector.Vec2
 	Velocity     vector.Vec2
 	Acceleration vector.Vec2
@@ -26,8 +41,35 @@ type Body struct {
 	SumTorque           float64
 
 	// Impulse
-	E float64 // coefficient of restitution
-	F float64 // coefficient of friction
+	E  float64 // coefficient of restitution
+	Fs float64 // coefficient of static friction, while the surfaces stick
+	Fd float64 // coefficient of dynamic friction, once they slide (Fd <= Fs)
+
+	FrictionCombine CombineRule // how Fs and Fd mix with the other body (see CombineRule)
+
+	/*
+		Speed of the surface along itself, the body doesn't move but the friction
+		drags whatever touches it (conveyor belts, wheels that spin in place).
+		Positive values go clockwise on the screen, the top of a box moves right.
+	*/
+	SurfaceVelocity float64
+
+	// Which bodies it can collide with
+	Filter Filter
+}
+
+var lastBodyID atomic.Uint64
+
+/*
+Number that identifies the body for as long as it lives, unlike its index in
+World.Bodies. It is given the first time it is asked for, bodies built as
+literals get one too.
+*/
+func (body *Body) ID() uint64 {
+	if body.id == 0 {
+		body.id = lastBodyID.Add(1)
+	}
+	return body.id
 }
 
 func (body *Body) AttachTexture(path string, rend *renderer.Renderer) {
@@ -35,23 +77,146 @@ func (body *Body) AttachTexture(path string, rend *renderer.Renderer) {
 	body.Texture = texture
 }
 
-func (body *Body) IntegrateLinear(dt float64) {
-	if body.Mass == 0 || body.Static {
+/*
+The integration is split in two so the collisions can be solved in between:
+forces change the velocities, the solver fixes the velocities and only then
+the velocities move the body.
+*/
+func (body *Body) IntegrateForces(dt float64) {
+	body.integrateLinearForces(dt)
+	body.integrateAngularForces(dt)
+}
+
+func (body *Body) IntegrateVelocities(dt float64) {
+	if !body.movable() {
 		return
 	}
 
-	body.Acceleration = body.SumForces.Multiply(1.0 / body.Mass)
-
-	// Update velocity first (semi-implicit Euler)
-	dampingFactor := 0.99
-	body.Velocity = body.Velocity.Add(body.Acceleration.Multiply(dt))
-	body.Velocity = body.Velocity.Multiply(dampingFactor)
-	// Then update position
 	body.Position = body.Position.Add(body.Velocity.Multiply(dt))
+	body.Rotation += body.AngularVelocity * dt
+	body.Shape.UpdateVertices(body.Position, body.Rotation)
+}
+
+/*
+Steps a body on its own, forces and velocities in one go. Deprecated: World.Update
+solves the collisions between IntegrateForces and IntegrateVelocities.
+*/
+func (body *Body) Update(dt float64) {
+	body.IntegrateForces(dt)
+	body.IntegrateVelocities(dt)
+}
+
+// Linear half of Update. Deprecated: use IntegrateForces and IntegrateVelocities.
+func (body *Body) IntegrateLinear(dt float64) {
+	body.integrateLinearForces(dt)
+	if body.movable() {
+		body.Position = body.Position.Add(body.Velocity.Multiply(dt))
+	}
+}
+
+// Angular half of Update. Deprecated: use IntegrateForces and IntegrateVelocities.
+func (body *Body) IntegrateAngular(dt float64) {
+	body.integrateAngularForces(dt)
+	if body.movable() {
+		body.Rotation += body.AngularVelocity * dt
+	}
+}
+
+// Only dynamic bodies care about forces
+func (body *Body) integrateLinearForces(dt float64) {
+	if body.Mass > 0 && body.IsDynamic() {
+		body.Acceleration = body.SumForces.Multiply(body.InvMass)
+
+		// Update velocity first (semi-implicit Euler)
+		dampingFactor := 0.99
+		body.Velocity = body.Velocity.Add(body.Acceleration.Multiply(dt))
+		body.Velocity = body.Velocity.Multiply(dampingFactor)
+	}
 
 	body.SumForces = vector.Vec2{X: 0, Y: 0}
 }
 
+func (body *Body) integrateAngularForces(dt float64) {
+	if body.Mass > 0 && body.IsDynamic() {
+		body.AngularAcceleration = body.SumTorque * body.InvInertia
+		body.AngularVelocity += body.AngularAcceleration * dt
+	}
+
+	body.SumTorque = 0
+}
+
+// Static bodies never move, and dynamic ones without mass neither
+func (body *Body) movable() bool {
+	return !body.IsStatic() && !(body.IsDynamic() && body.Mass == 0)
+}
+
+/*
+The mass comes from the size of the shape, bigger bodies of the same
+material weigh more:
+
+	Mass = Density * Shape.Area()
+*/
+func (body *Body) SetDensity(density float64) {
+	// Compound bodies give the density to all of their fixtures
+	if compound, ok := body.Shape.(*Compound); ok {
+		for _, fixture := range compound.Fixtures {
+			fixture.Density = density
+		}
+	}
+
+	body.SetMass(density * body.Shape.Area())
+}
+
+/*
+Sets the total mass instead, the density is whatever spreads that mass over
+the shape. Both come with the inertia, the moments of inertia of the shapes
+are per unit of mass, so:
+
+	Inertia = Shape.MomentOfInertia() * Mass
+
+Static and kinematic bodies keep their Mass but get 0 inverses, for the
+solver they have infinite mass. A dynamic body with 0 mass doesn't move.
+*/
+func (body *Body) SetMass(mass float64) {
+	body.Mass = mass
+	body.Density = 0
+	if area := body.Shape.Area(); area > 0 {
+		body.Density = mass / area
+	}
+	body.Inertia = body.Shape.MomentOfInertia() * mass
+	body.InvMass = 0
+	body.InvInertia = 0
+
+	if !body.IsDynamic() {
+		return
+	}
+	if body.Mass > 0 {
+		body.InvMass = 1 / body.Mass
+	}
+	if body.Inertia > 0 {
+		body.InvInertia = 1 / body.Inertia
+	}
+}
+
+/*
+Recomputes everything, needed after changing the Shape or the Type. The
+density is kept, so a shape that grew makes the body heavier. Compound
+bodies add up the masses of their fixtures. Bodies without density (built
+by hand) keep their Mass.
+*/
+func (body *Body) ResetMassData() {
+	if compound, ok := body.Shape.(*Compound); ok && compound.Mass() > 0 {
+		body.SetMass(compound.Mass())
+		return
+	}
+
+	if body.Density > 0 {
+		body.SetDensity(body.Density)
+		return
+	}
+	body.SetMass(body.Mass)
+}
+
 func (body *Body) AngularVelocityProduct(r vector.Vec2) vector.Vec2 {
 	/*
 		Cross product result in two dimentions
@@ -65,37 +230,77 @@ func (body *Body) AngularVelocityProduct(r vector.Vec2) vector.Vec2 {
 	return vector.Vec2{X: -body.AngularVelocity * r.Y, Y: body.AngularVelocity * r.X}
 }
 
-func NewCircle(position vector.Vec2, radius int32, color uint32, mass float64) Body {
+/*
+The constructors get the density (see constants.DEFAULT_DENSITY), call
+SetMass afterwards to pick the total mass instead.
+*/
+func NewCircle(position vector.Vec2, radius int32, color uint32, density float64) Body {
 	circleShape := CircleShape(radius, color)
 	circle := Body{
 		Position: position,
-		Mass:     mass,
-		InvMass:  1 / mass,
 		Shape:    circleShape,
 		Rotation: 0,
-		Static:   false,
 		E:        1,
-		F:        1,
+		Fs:       1,
+		Fd:       0.8,
+		Filter:   DefaultFilter(),
 		Name:     "Circle",
 	}
+	circle.SetDensity(density)
 	return circle
 }
 
-func NewBoxBody(color uint32, width float64, height float64, mass float64, position vector.Vec2, rotation float64, static bool) Body {
+func NewBoxBody(color uint32, width float64, height float64, density float64, position vector.Vec2, rotation float64, bodyType BodyType) Body {
 	newBoxShape := NewBox(color, width, height)
 	box := Body{
 		Position: position,
-		Mass:     mass,
-		InvMass:  1 / mass,
 		Shape:    newBoxShape,
 		Rotation: rotation,
-		Static:   static,
+		Type:     bodyType,
 		E:        1,
-		F:        1,
+		Fs:       1,
+		Fd:       0.8,
+		Filter:   DefaultFilter(),
 	}
+	box.SetDensity(density)
 	return box
 }
 
+// Convex polygon body, the vertices are recentered on the centroid (see NewPolygon).
+func NewPolygonBody(color uint32, vertices []vector.Vec2, density float64, position vector.Vec2, rotation float64, bodyType BodyType) Body {
+	polygon := Body{
+		Position: position,
+		Shape:    NewPolygon(color, vertices),
+		Rotation: rotation,
+		Type:     bodyType,
+		E:        1,
+		Fs:       1,
+		Fd:       0.8,
+		Filter:   DefaultFilter(),
+	}
+	polygon.SetDensity(density)
+	return polygon
+}
+
+/*
+Body made of several shapes, each fixture brings its own material and filter
+(see Fixture). The mass comes from the densities of the fixtures.
+*/
+func NewCompoundBody(position vector.Vec2, rotation float64, bodyType BodyType, fixtures ...*Fixture) Body {
+	compound := Body{
+		Position: position,
+		Shape:    NewCompound(fixtures...),
+		Rotation: rotation,
+		Type:     bodyType,
+		E:        1,
+		Fs:       1,
+		Fd:       0.8,
+		Filter:   DefaultFilter(),
+	}
+	compound.ResetMassData()
+	return compound
+}
+
 func (body *Body) ApplyImpulse(J vector.Vec2, r vector.Vec2) {
 	body.applyLinearImpulse(J)
 	body.applyAngularImpulse(J, r)
@@ -106,24 +311,11 @@ func (body *Body) applyLinearImpulse(J vector.Vec2) {
 }
 
 func (body *Body) applyAngularImpulse(J vector.Vec2, r vector.Vec2) {
-	body.AngularVelocity = body.AngularVelocity + r.Cross(J)/body.Shape.MomentOfInertia()
+	body.AngularVelocity = body.AngularVelocity + r.Cross(J)*body.InvInertia
 }
 
-func (body *Body) IntegrateAngular(dt float64) {
-	if body.Mass == 0 || body.Static {
-		return
-	}
-
-	body.AngularAcceleration = body.SumTorque * (1 / (body.Shape.MomentOfInertia() * body.Mass))
-	body.AngularVelocity += body.AngularAcceleration * dt
-	body.Rotation += body.AngularVelocity * dt
-	body.SumTorque = 0
-}
-
-func (body *Body) Update(dt float64) {
-	body.IntegrateLinear(dt)
-	body.IntegrateAngular(dt)
-	body.Shape.UpdateVertices(body.Position, body.Rotation)
+func (body *Body) GetAABB() AABB {
+	return body.Shape.GetAABB()
 }
 
 func (body *Body) Destroy() {
@@ -0,0 +1,30 @@
+package entities
+
+/*
+How a body moves:
+
+  - BODY_DYNAMIC: moved by forces and collisions, the default.
+  - BODY_STATIC: never moves (floors, walls).
+  - BODY_KINEMATIC: moves with the Velocity and AngularVelocity set by the
+    game, forces and collisions don't change them. For the other bodies it
+    has infinite mass, so it pushes them around (moving platforms, elevators).
+*/
+type BodyType int
+
+const (
+	BODY_DYNAMIC BodyType = iota
+	BODY_STATIC
+	BODY_KINEMATIC
+)
+
+func (body *Body) IsDynamic() bool {
+	return body.Type == BODY_DYNAMIC
+}
+
+func (body *Body) IsStatic() bool {
+	return body.Type == BODY_STATIC
+}
+
+func (body *Body) IsKinematic() bool {
+	return body.Type == BODY_KINEMATIC
+}
@@ -0,0 +1,153 @@
+package entities
+
+import (
+	"engine/renderer"
+	"engine/vector"
+	"math"
+)
+
+/*
+Segment with a radius, every point closer than Radius to the segment is
+inside of the capsule:
+
+	  .-----.
+	 /       \
+	|    A    |
+	|    |    |
+	|    |    |
+	|    B    |
+	 \       /
+	  '-----'
+
+It has no corners, so it slides over the edges where a box would snag
+(characters, limbs...).
+*/
+type Capsule struct {
+	Color  uint32
+	Radius float64
+	LocalA vector.Vec2
+	LocalB vector.Vec2
+	A      vector.Vec2 // world positions of the ends of the segment, refreshed by UpdateVertices
+	B      vector.Vec2
+}
+
+/*
+Standing capsule, length is the distance between the centers of both caps,
+so it is length + 2 * radius tall. Rotate the body to lay it down.
+*/
+func NewCapsule(color uint32, length float64, radius float64) *Capsule {
+	capsule := &Capsule{
+		Color:  color,
+		Radius: radius,
+		LocalA: vector.Vec2{X: 0, Y: -length / 2},
+		LocalB: vector.Vec2{X: 0, Y: length / 2},
+	}
+	capsule.A = capsule.LocalA
+	capsule.B = capsule.LocalB
+	return capsule
+}
+
+func (capsule *Capsule) length() float64 {
+	segment := capsule.LocalB.Subtract(capsule.LocalA)
+	return segment.Magnitude()
+}
+
+// A box of length x 2 * radius plus a circle split in two caps.
+func (capsule *Capsule) Area() float64 {
+	return 2*capsule.Radius*capsule.length() + math.Pi*capsule.Radius*capsule.Radius
+}
+
+/*
+Per unit of mass and around the middle of the segment, adding up the box and
+the two caps:
+
+	box:  (length^2 + (2 * radius)^2) / 12
+
+Each cap is half a circle, around the center of its flat side that is still
+radius^2 / 2. Its centroid is d = 4 * radius / (3 * pi) away from the flat
+side, the parallel axis theorem moves it to the middle of the capsule:
+
+	caps: radius^2 / 2 - d^2 + (length / 2 + d)^2 = radius^2 / 2 + length^2 / 4 + length * d
+
+Both weighted by their area.
+*/
+func (capsule *Capsule) MomentOfInertia() float64 {
+	radius := capsule.Radius
+	length := capsule.length()
+
+	boxArea := 2 * radius * length
+	capsArea := math.Pi * radius * radius
+	if boxArea+capsArea == 0 {
+		return 0
+	}
+
+	d := 4 * radius / (3 * math.Pi)
+	box := (length*length + 4*radius*radius) / 12
+	caps := radius*radius/2 + length*length/4 + length*d
+
+	// The segment might not be centered on the body
+	sum := capsule.LocalA.Add(capsule.LocalB)
+	middle := sum.Multiply(0.5)
+	return (boxArea*box+capsArea*caps)/(boxArea+capsArea) + middle.Dot(middle)
+}
+
+// Size of the bounding box of the capsule before rotating it (textures are drawn this big).
+func (capsule *Capsule) GetHeight() float64 {
+	return math.Abs(capsule.LocalB.Y-capsule.LocalA.Y) + 2*capsule.Radius
+}
+
+func (capsule *Capsule) GetWidth() float64 {
+	return math.Abs(capsule.LocalB.X-capsule.LocalA.X) + 2*capsule.Radius
+}
+
+/*
+Two sides parallel to the segment and half a circle on each end, facing out
+of the segment.
+*/
+func (capsule *Capsule) Draw(body *Body, rendr *renderer.Renderer) {
+	segment := capsule.B.Subtract(capsule.A)
+	if segment.Magnitude() == 0 {
+		rendr.DrawCircle(int32(capsule.A.X), int32(capsule.A.Y), int32(capsule.Radius), body.Rotation, capsule.Color)
+		return
+	}
+
+	side := segment.Normal()
+	side = side.Multiply(capsule.Radius)
+	rendr.DrawLine(capsule.A.Add(side), capsule.B.Add(side), capsule.Color)
+	rendr.DrawLine(capsule.A.Subtract(side), capsule.B.Subtract(side), capsule.Color)
+
+	angle := math.Atan2(segment.Y, segment.X)
+	radius := int32(capsule.Radius)
+	rendr.DrawArc(int32(capsule.B.X), int32(capsule.B.Y), radius, angle-math.Pi/2, angle+math.Pi/2, capsule.Color)
+	rendr.DrawArc(int32(capsule.A.X), int32(capsule.A.Y), radius, angle+math.Pi/2, angle+3*math.Pi/2, capsule.Color)
+}
+
+func (capsule *Capsule) UpdateVertices(position vector.Vec2, rotation float64) {
+	capsule.A = capsule.LocalA.Rotate(rotation)
+	capsule.A = capsule.A.Add(position)
+	capsule.B = capsule.LocalB.Rotate(rotation)
+	capsule.B = capsule.B.Add(position)
+}
+
+func (capsule *Capsule) GetAABB() AABB {
+	aabb := AABBFromVertices([]vector.Vec2{capsule.A, capsule.B})
+	return aabb.Expand(capsule.Radius)
+}
+
+func (capsule *Capsule) Support(direction vector.Vec2) vector.Vec2 {
+	end := capsule.A
+	if direction.Dot(capsule.B) > direction.Dot(capsule.A) {
+		end = capsule.B
+	}
+
+	unit := direction.Unit()
+	return end.Add(unit.Multiply(capsule.Radius))
+}
+
+func (capsule *Capsule) MarkDebug() {
+	capsule.Color = renderer.DEBUG
+}
+
+func (capsule *Capsule) UnMarkDebug() {
+	capsule.Color = renderer.WHITE
+}
@@ -0,0 +1,133 @@
+package entities
+
+import (
+	"engine/renderer"
+	"engine/vector"
+	"fmt"
+	"math"
+)
+
+/*
+Terrain made of segments joined end to end, vertices[i] to vertices[i+1].
+A looped chain also joins the last vertex with the first one.
+
+Every segment gets its neighbours as ghost vertices (see Segment), so the
+bodies go over the joints as if the chain was one smooth line. The segments
+are the fixtures of the body (see Body.Fixtures), the narrowphase only tests
+the ones close to the other body.
+
+It has no area, it is meant for static bodies.
+*/
+type Chain struct {
+	Color    uint32
+	Loop     bool
+	Segments []*Segment
+}
+
+/*
+The vertices are relative to the position of the body, like NewSegment. It
+needs at least 2 of them, 3 to loop.
+*/
+func NewChain(color uint32, vertices []vector.Vec2, loop bool) *Chain {
+	count := len(vertices)
+	if count < 2 || (loop && count < 3) {
+		panic(fmt.Sprintf("entities: NewChain got %d vertices, it needs at least 2 (3 for a loop)", count))
+	}
+
+	chain := &Chain{Color: color, Loop: loop}
+	segments := count - 1
+	if loop {
+		segments = count
+	}
+
+	for i := 0; i < segments; i++ {
+		segment := NewSegment(color, vertices[i], vertices[(i+1)%count])
+
+		if i > 0 || loop {
+			segment.LocalGhostA = vertices[(i-1+count)%count]
+			segment.HasGhostA = true
+		}
+		if i < count-2 || loop {
+			segment.LocalGhostB = vertices[(i+2)%count]
+			segment.HasGhostB = true
+		}
+
+		chain.Segments = append(chain.Segments, segment)
+	}
+	return chain
+}
+
+func (chain *Chain) Area() float64 {
+	return 0
+}
+
+func (chain *Chain) MomentOfInertia() float64 {
+	return 0
+}
+
+func (chain *Chain) GetHeight() float64 {
+	aabb := chain.localAABB()
+	return aabb.Max.Y - aabb.Min.Y
+}
+
+func (chain *Chain) GetWidth() float64 {
+	aabb := chain.localAABB()
+	return aabb.Max.X - aabb.Min.X
+}
+
+func (chain *Chain) localAABB() AABB {
+	var vertices []vector.Vec2
+	for _, segment := range chain.Segments {
+		vertices = append(vertices, segment.LocalA, segment.LocalB)
+	}
+	return AABBFromVertices(vertices)
+}
+
+func (chain *Chain) Draw(body *Body, rendr *renderer.Renderer) {
+	for _, segment := range chain.Segments {
+		segment.Draw(body, rendr)
+	}
+}
+
+func (chain *Chain) UpdateVertices(position vector.Vec2, rotation float64) {
+	for _, segment := range chain.Segments {
+		segment.UpdateVertices(position, rotation)
+	}
+}
+
+func (chain *Chain) GetAABB() AABB {
+	aabb := AABB{
+		Min: vector.Vec2{X: math.Inf(1), Y: math.Inf(1)},
+		Max: vector.Vec2{X: math.Inf(-1), Y: math.Inf(-1)},
+	}
+	for _, segment := range chain.Segments {
+		aabb = aabb.Union(segment.GetAABB())
+	}
+	return aabb
+}
+
+// Support point of the convex hull of the chain, the narrowphase goes through the segments.
+func (chain *Chain) Support(direction vector.Vec2) vector.Vec2 {
+	support := chain.Segments[0].Support(direction)
+	best := direction.Dot(support)
+	for _, segment := range chain.Segments[1:] {
+		point := segment.Support(direction)
+		if projection := direction.Dot(point); projection > best {
+			best = projection
+			support = point
+		}
+	}
+	return support
+}
+
+func (chain *Chain) MarkDebug() {
+	for _, segment := range chain.Segments {
+		segment.MarkDebug()
+	}
+}
+
+func (chain *Chain) UnMarkDebug() {
+	for _, segment := range chain.Segments {
+		segment.UnMarkDebug()
+	}
+}
@@ -0,0 +1,30 @@
+package entities
+
+import (
+	"engine/renderer"
+	"engine/vector"
+	"testing"
+)
+
+func TestNewChainTooFewVertices(t *testing.T) {
+	cases := []struct {
+		name     string
+		vertices []vector.Vec2
+		loop     bool
+	}{
+		{"empty", nil, false},
+		{"one vertex", []vector.Vec2{{X: 0, Y: 0}}, false},
+		{"loop of two", []vector.Vec2{{X: 0, Y: 0}, {X: 10, Y: 0}}, true},
+	}
+
+	for _, c := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: NewChain didn't panic", c.name)
+				}
+			}()
+			NewChain(renderer.WHITE, c.vertices, c.loop)
+		}()
+	}
+}
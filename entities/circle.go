@@ -3,11 +3,13 @@ package entities
 import (
 	"engine/renderer"
 	"engine/vector"
+	"math"
 )
 
 type Circle struct {
 	Color  uint32
 	Radius int32
+	Center vector.Vec2 // world position, refreshed by UpdateVertices
 }
 
 func CircleShape(radius int32, color uint32) *Circle {
@@ -21,6 +23,10 @@ func (circle *Circle) MomentOfInertia() float64 {
 	return 0.5 * float64(circle.Radius) * float64(circle.Radius)
 }
 
+func (circle *Circle) Area() float64 {
+	return math.Pi * float64(circle.Radius) * float64(circle.Radius)
+}
+
 func (circle *Circle) GetHeight() float64 {
 	return 2 * float64(circle.Radius)
 }
@@ -32,8 +38,8 @@ func (circle *Circle) GetWidth() float64 {
 func (circle *Circle) Draw(body *Body, renderer *renderer.Renderer) {
 	// fmt.Printf("drawing x %f, y %f\n", x, y)
 	renderer.DrawCircle(
-		int32(body.Position.X),
-		int32(body.Position.Y),
+		int32(circle.Center.X),
+		int32(circle.Center.Y),
 		circle.Radius,
 		body.Rotation,
 		circle.Color,
@@ -41,7 +47,20 @@ func (circle *Circle) Draw(body *Body, renderer *renderer.Renderer) {
 }
 
 func (circle *Circle) UpdateVertices(position vector.Vec2, rotation float64) {
-	return // no-op
+	circle.Center = position
+}
+
+func (circle *Circle) GetAABB() AABB {
+	radius := float64(circle.Radius)
+	return AABB{
+		Min: vector.Vec2{X: circle.Center.X - radius, Y: circle.Center.Y - radius},
+		Max: vector.Vec2{X: circle.Center.X + radius, Y: circle.Center.Y + radius},
+	}
+}
+
+func (circle *Circle) Support(direction vector.Vec2) vector.Vec2 {
+	unit := direction.Unit()
+	return circle.Center.Add(unit.Multiply(float64(circle.Radius)))
 }
 
 func (circle *Circle) MarkDebug() {
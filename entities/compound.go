@@ -0,0 +1,193 @@
+package entities
+
+import (
+	"engine/renderer"
+	"engine/vector"
+	"math"
+)
+
+/*
+Shape made of several fixtures, for bodies a single convex shape can't
+describe (an L shaped table, a hammer...). Each fixture is tested on its own
+by the narrowphase, but they all belong to the same body.
+
+The mass properties add up the ones of the fixtures, heavier fixtures pull
+the center of mass towards them. Fixtures without density count by their
+area, as if all of them were made of the same material.
+*/
+type Compound struct {
+	Fixtures []*Fixture
+}
+
+/*
+The offsets of the fixtures are moved so the center of mass ends up at
+(0, 0), that is where the body rotates around (same as NewPolygon). The
+compound gets copies of the fixtures, the ones passed in keep their offsets
+(the shapes are still shared). It needs at least one fixture.
+*/
+func NewCompound(fixtures ...*Fixture) *Compound {
+	if len(fixtures) == 0 {
+		panic("entities: NewCompound needs at least one fixture")
+	}
+
+	compound := &Compound{Fixtures: make([]*Fixture, len(fixtures))}
+	for i, fixture := range fixtures {
+		copied := *fixture
+		compound.Fixtures[i] = &copied
+	}
+	fixtures = compound.Fixtures
+
+	/*
+		centroid = sum(mi * offseti) / sum(mi)
+	*/
+	var centroid vector.Vec2
+	total := 0.0
+	for i, weight := range compound.weights() {
+		offset := fixtures[i].Offset
+		centroid = centroid.Add(offset.Multiply(weight))
+		total += weight
+	}
+	if total > 0 {
+		centroid = centroid.Multiply(1 / total)
+	}
+
+	for _, fixture := range fixtures {
+		fixture.Offset = fixture.Offset.Subtract(centroid)
+	}
+	return compound
+}
+
+// How much each fixture counts in the mass properties, its mass or its area if no fixture has density.
+func (compound *Compound) weights() []float64 {
+	weights := make([]float64, len(compound.Fixtures))
+	for i, fixture := range compound.Fixtures {
+		weights[i] = fixture.Density * fixture.Shape.Area()
+	}
+
+	if compound.Mass() == 0 {
+		for i, fixture := range compound.Fixtures {
+			weights[i] = fixture.Shape.Area()
+		}
+	}
+	return weights
+}
+
+// Sum of the masses of the fixtures, 0 if none of them has density.
+func (compound *Compound) Mass() float64 {
+	mass := 0.0
+	for _, fixture := range compound.Fixtures {
+		mass += fixture.Density * fixture.Shape.Area()
+	}
+	return mass
+}
+
+func (compound *Compound) Area() float64 {
+	area := 0.0
+	for _, fixture := range compound.Fixtures {
+		area += fixture.Shape.Area()
+	}
+	return area
+}
+
+/*
+Per unit of mass, like the rest of the shapes. The inertia of each fixture
+is around its own center, the parallel axis theorem moves it to the center
+of the body:
+
+	I = sum(mi * (Ii + |offseti|^2)) / sum(mi)
+*/
+func (compound *Compound) MomentOfInertia() float64 {
+	inertia := 0.0
+	total := 0.0
+	for i, weight := range compound.weights() {
+		offset := compound.Fixtures[i].Offset
+		inertia += weight * (compound.Fixtures[i].Shape.MomentOfInertia() + offset.Dot(offset))
+		total += weight
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return inertia / total
+}
+
+func (compound *Compound) UpdateVertices(position vector.Vec2, rotation float64) {
+	for _, fixture := range compound.Fixtures {
+		offset := fixture.Offset.Rotate(rotation)
+		fixture.Shape.UpdateVertices(position.Add(offset), rotation+fixture.Rotation)
+	}
+}
+
+func (compound *Compound) Draw(body *Body, rendr *renderer.Renderer) {
+	for _, fixture := range compound.Fixtures {
+		fixture.Shape.Draw(body, rendr)
+	}
+}
+
+// A compound without fixtures (built by hand) is an empty box at (0, 0).
+func (compound *Compound) GetAABB() AABB {
+	if len(compound.Fixtures) == 0 {
+		return AABB{}
+	}
+
+	aabb := compound.Fixtures[0].Shape.GetAABB()
+	for _, fixture := range compound.Fixtures[1:] {
+		aabb = aabb.Union(fixture.Shape.GetAABB())
+	}
+	return aabb
+}
+
+// Size of the box around the fixtures before rotating the body, the rotation of each fixture is ignored.
+func (compound *Compound) GetWidth() float64 {
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	for _, fixture := range compound.Fixtures {
+		half := fixture.Shape.GetWidth() / 2
+		minX = math.Min(minX, fixture.Offset.X-half)
+		maxX = math.Max(maxX, fixture.Offset.X+half)
+	}
+	return maxX - minX
+}
+
+func (compound *Compound) GetHeight() float64 {
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, fixture := range compound.Fixtures {
+		half := fixture.Shape.GetHeight() / 2
+		minY = math.Min(minY, fixture.Offset.Y-half)
+		maxY = math.Max(maxY, fixture.Offset.Y+half)
+	}
+	return maxY - minY
+}
+
+/*
+The compound isn't convex, this is the support point of its convex hull.
+The narrowphase never uses it, it goes through the fixtures one by one.
+Without fixtures it is (0, 0), like GetAABB.
+*/
+func (compound *Compound) Support(direction vector.Vec2) vector.Vec2 {
+	if len(compound.Fixtures) == 0 {
+		return vector.Vec2{}
+	}
+
+	support := compound.Fixtures[0].Shape.Support(direction)
+	best := direction.Dot(support)
+	for _, fixture := range compound.Fixtures[1:] {
+		point := fixture.Shape.Support(direction)
+		if projection := direction.Dot(point); projection > best {
+			best = projection
+			support = point
+		}
+	}
+	return support
+}
+
+func (compound *Compound) MarkDebug() {
+	for _, fixture := range compound.Fixtures {
+		fixture.Shape.MarkDebug()
+	}
+}
+
+func (compound *Compound) UnMarkDebug() {
+	for _, fixture := range compound.Fixtures {
+		fixture.Shape.UnMarkDebug()
+	}
+}
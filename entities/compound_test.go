@@ -0,0 +1,38 @@
+package entities
+
+import (
+	"engine/renderer"
+	"engine/vector"
+	"testing"
+)
+
+func TestNewCompoundKeepsFixtures(t *testing.T) {
+	left := NewFixture(NewBox(renderer.WHITE, 10, 10), vector.Vec2{X: 0, Y: 0}, 0, 1)
+	right := NewFixture(NewBox(renderer.WHITE, 10, 10), vector.Vec2{X: 20, Y: 0}, 0, 1)
+
+	compound := NewCompound(left, right)
+
+	if left.Offset != (vector.Vec2{X: 0, Y: 0}) || right.Offset != (vector.Vec2{X: 20, Y: 0}) {
+		t.Errorf("offsets of the fixtures passed in changed to %v and %v", left.Offset, right.Offset)
+	}
+	if got := compound.Fixtures[0].Offset; got != (vector.Vec2{X: -10, Y: 0}) {
+		t.Errorf("offset of the first fixture %v, want {-10 0}", got)
+	}
+}
+
+func TestEmptyCompound(t *testing.T) {
+	compound := &Compound{}
+	if aabb := compound.GetAABB(); aabb != (AABB{}) {
+		t.Errorf("AABB %v, want an empty one", aabb)
+	}
+	if support := compound.Support(vector.Vec2{X: 1, Y: 0}); support != (vector.Vec2{}) {
+		t.Errorf("support %v, want {0 0}", support)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewCompound without fixtures didn't panic")
+		}
+	}()
+	NewCompound()
+}
@@ -0,0 +1,103 @@
+package entities
+
+/*
+Decides which bodies can collide, it is checked before the narrowphase.
+
+Every body belongs to the categories set in Category and collides with the
+categories set in Mask. Both bodies have to accept each other:
+
+	(A.Category & B.Mask) != 0 && (B.Category & A.Mask) != 0
+
+Group overrides the bits for bodies of the same group: a positive group
+always collides with itself, a negative one never does (debris, the bullets
+of one gun and the gun itself...). 0 means no group.
+
+The zero value is the default filter, a body built without one collides with
+everything. Only a filter with both Category and Mask left at 0 counts as
+unset, Filter{Category: PLAYER} collides with nothing until it gets a Mask.
+
+The filter is checked every step, so it can be changed at any time.
+*/
+type Filter struct {
+	Category uint16
+	Mask     uint16
+	Group    int16
+}
+
+const (
+	CATEGORY_DEFAULT uint16 = 0x0001
+	MASK_ALL         uint16 = 0xFFFF
+)
+
+// Collides with everything.
+func DefaultFilter() Filter {
+	return Filter{Category: CATEGORY_DEFAULT, Mask: MASK_ALL}
+}
+
+func (filter *Filter) ShouldCollide(other *Filter) bool {
+	if filter.Group != 0 && filter.Group == other.Group {
+		return filter.Group > 0
+	}
+
+	category, mask := filter.bits()
+	otherCategory, otherMask := other.bits()
+	return category&otherMask != 0 && otherCategory&mask != 0
+}
+
+// Category and Mask, or the ones of DefaultFilter if the filter was never set.
+func (filter *Filter) bits() (uint16, uint16) {
+	if filter.Category == 0 && filter.Mask == 0 {
+		return CATEGORY_DEFAULT, MASK_ALL
+	}
+	return filter.Category, filter.Mask
+}
+
+/*
+Helper to think in layers instead of bits, like the collision matrix of most
+editors. Each of the 16 layers is one category bit, all the layers collide
+with each other until told otherwise:
+
+	const (
+		PLAYER = iota // layers go from 0 to 15
+		PLAYER_BULLETS
+		ENEMIES
+	)
+
+	layers := entities.NewLayerMatrix()
+	layers.SetCollision(PLAYER, PLAYER_BULLETS, false)
+	player.Filter = layers.Filter(PLAYER)
+*/
+type LayerMatrix struct {
+	masks [16]uint16
+}
+
+func NewLayerMatrix() *LayerMatrix {
+	matrix := &LayerMatrix{}
+	for i := range matrix.masks {
+		matrix.masks[i] = MASK_ALL
+	}
+	return matrix
+}
+
+// The matrix is symmetric, layerA vs layerB is the same as layerB vs layerA.
+func (matrix *LayerMatrix) SetCollision(layerA int, layerB int, collide bool) {
+	if collide {
+		matrix.masks[layerA] |= 1 << layerB
+		matrix.masks[layerB] |= 1 << layerA
+	} else {
+		matrix.masks[layerA] &^= 1 << layerB
+		matrix.masks[layerB] &^= 1 << layerA
+	}
+}
+
+func (matrix *LayerMatrix) Collides(layerA int, layerB int) bool {
+	return matrix.masks[layerA]&(1<<layerB) != 0
+}
+
+/*
+Filter for a body on layer. Bodies that got their filter before the matrix
+changed have to get it again.
+*/
+func (matrix *LayerMatrix) Filter(layer int) Filter {
+	return Filter{Category: 1 << layer, Mask: matrix.masks[layer]}
+}
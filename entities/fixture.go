@@ -0,0 +1,71 @@
+package entities
+
+import "engine/vector"
+
+/*
+One of the shapes of a compound body (see Compound). The shape sits at
+Offset, turned Rotation radians, in the local space of the body, and it has
+its own material and filter: a hammer can have a heavy head and a light
+handle, the bumper of a car can ignore the player...
+
+The fixtures don't move on their own, their contacts push the whole body.
+*/
+type Fixture struct {
+	Shape    Shape
+	Offset   vector.Vec2
+	Rotation float64
+
+	// Same as the ones of the Body, but only for this shape
+	Density         float64
+	E               float64
+	Fs              float64
+	Fd              float64
+	FrictionCombine CombineRule
+	Filter          Filter
+}
+
+// Same material as the bodies made by the constructors.
+func NewFixture(shape Shape, offset vector.Vec2, rotation float64, density float64) *Fixture {
+	return &Fixture{
+		Shape:    shape,
+		Offset:   offset,
+		Rotation: rotation,
+		Density:  density,
+		E:        1,
+		Fs:       1,
+		Fd:       0.8,
+		Filter:   DefaultFilter(),
+	}
+}
+
+/*
+Shapes of the body with their materials. A body with a single shape gets
+one fixture made out of the body itself, and a chain one per segment, so the
+narrowphase doesn't need to tell them apart.
+*/
+func (body *Body) Fixtures() []*Fixture {
+	switch shape := body.Shape.(type) {
+	case *Compound:
+		return shape.Fixtures
+	case *Chain:
+		fixtures := make([]*Fixture, len(shape.Segments))
+		for i, segment := range shape.Segments {
+			fixtures[i] = body.fixture(segment)
+		}
+		return fixtures
+	default:
+		return []*Fixture{body.fixture(body.Shape)}
+	}
+}
+
+func (body *Body) fixture(shape Shape) *Fixture {
+	return &Fixture{
+		Shape:           shape,
+		Density:         body.Density,
+		E:               body.E,
+		Fs:              body.Fs,
+		Fd:              body.Fd,
+		FrictionCombine: body.FrictionCombine,
+		Filter:          body.Filter,
+	}
+}
@@ -0,0 +1,45 @@
+package entities
+
+import "math"
+
+/*
+How the friction coefficients of two touching bodies are mixed into the one
+used by the contact. When the bodies ask for different rules the one declared
+last below wins: a body with COMBINE_MAX is always grippy, a body with
+COMBINE_MIN is slippery against everything except COMBINE_MAX bodies (rubber
+on ice grips).
+*/
+type CombineRule int
+
+const (
+	COMBINE_GEOMETRIC_MEAN CombineRule = iota // sqrt(a * b), the default
+	COMBINE_AVERAGE                           // (a + b) / 2
+	COMBINE_MULTIPLY                          // a * b
+	COMBINE_MIN                               // ice wins
+	COMBINE_MAX                               // rubber wins
+)
+
+// The materials come from the fixtures that touch, for most bodies that is the body itself (see Body.Fixtures).
+func CombineFriction(fixtureA *Fixture, fixtureB *Fixture) (static float64, dynamic float64) {
+	rule := fixtureA.FrictionCombine
+	if fixtureB.FrictionCombine > rule {
+		rule = fixtureB.FrictionCombine
+	}
+
+	return rule.Combine(fixtureA.Fs, fixtureB.Fs), rule.Combine(fixtureA.Fd, fixtureB.Fd)
+}
+
+func (rule CombineRule) Combine(a float64, b float64) float64 {
+	switch rule {
+	case COMBINE_AVERAGE:
+		return (a + b) / 2
+	case COMBINE_MULTIPLY:
+		return a * b
+	case COMBINE_MIN:
+		return math.Min(a, b)
+	case COMBINE_MAX:
+		return math.Max(a, b)
+	default:
+		return math.Sqrt(a * b)
+	}
+}
@@ -0,0 +1,195 @@
+package entities
+
+import (
+	"engine/vector"
+	"math"
+)
+
+/*
+Scripted movement for kinematic bodies (moving platforms, elevators...). The
+body goes from waypoint to waypoint at Speed pix/s on average, the easing curve
+shapes how fast it goes along each leg, and waits Pause seconds on every
+waypoint.
+
+The path never touches the Position of the body, every step it sets the
+Velocity that gets the body where it should be at the end of the step. That
+way the solver knows how fast the platform moves and the bodies riding it
+move with it.
+
+	platform.Path = entities.NewPath(entities.PATH_PING_PONG, 100,
+		entities.Waypoint{Position: vector.Vec2{X: 200, Y: 400}, Pause: 1},
+		entities.Waypoint{Position: vector.Vec2{X: 600, Y: 400}, Pause: 1},
+	)
+
+The body should start on the first waypoint, otherwise it gets there in the
+first step.
+*/
+type Path struct {
+	Waypoints []Waypoint
+	Speed     float64
+	Mode      PathMode
+	Easing    Easing
+
+	// Leg the body is on, from the waypoint at from to the one at to
+	from    int
+	to      int
+	elapsed float64 // seconds since the leg started
+	waiting float64 // seconds of pause left, the leg starts after it
+	done    bool
+}
+
+type Waypoint struct {
+	Position vector.Vec2
+	Pause    float64 // seconds the body waits here
+}
+
+/*
+What happens at the last waypoint:
+
+  - PATH_ONCE: the body stops there.
+  - PATH_LOOP: it goes back to the first waypoint and starts again.
+  - PATH_PING_PONG: it goes through the waypoints backwards, then forwards...
+*/
+type PathMode int
+
+const (
+	PATH_ONCE PathMode = iota
+	PATH_LOOP
+	PATH_PING_PONG
+)
+
+/*
+Maps the fraction of the leg that passed (0 to 1) to the fraction of the
+distance travelled (0 to 1). Anything that starts at 0 and ends at 1 works.
+*/
+type Easing func(t float64) float64
+
+func EaseLinear(t float64) float64 {
+	return t
+}
+
+func EaseInQuad(t float64) float64 {
+	return t * t
+}
+
+func EaseOutQuad(t float64) float64 {
+	return t * (2 - t)
+}
+
+func EaseInOutQuad(t float64) float64 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return 1 - 2*(1-t)*(1-t)
+}
+
+// Starts and stops smoothly, good for platforms that pause on the waypoints.
+func EaseInOutSine(t float64) float64 {
+	return (1 - math.Cos(math.Pi*t)) / 2
+}
+
+func NewPath(mode PathMode, speed float64, waypoints ...Waypoint) *Path {
+	path := &Path{
+		Waypoints: waypoints,
+		Speed:     speed,
+		Mode:      mode,
+		Easing:    EaseLinear,
+	}
+	path.Reset()
+	return path
+}
+
+// Back to the first waypoint, the body has to be moved there by hand.
+func (path *Path) Reset() {
+	path.from = 0
+	path.to = 1
+	path.elapsed = 0
+	path.done = false
+	if len(path.Waypoints) > 0 {
+		path.waiting = path.Waypoints[0].Pause
+	}
+}
+
+// True once a PATH_ONCE path got to its last waypoint.
+func (path *Path) Done() bool {
+	return path.done
+}
+
+/*
+Sets the velocity that takes the body to where the path is at the end of the
+step. It is called by the world before the collisions are solved, only for
+kinematic bodies.
+*/
+func (body *Body) FollowPath(dt float64) {
+	if body.Path == nil || len(body.Path.Waypoints) == 0 || dt == 0 {
+		return
+	}
+
+	target := body.Path.advance(dt)
+	displacement := target.Subtract(body.Position)
+	body.Velocity = displacement.Multiply(1 / dt)
+}
+
+// Moves the path forward by dt and returns where the body should be.
+func (path *Path) advance(dt float64) vector.Vec2 {
+	if len(path.Waypoints) < 2 || path.done || path.Speed <= 0 {
+		return path.Waypoints[path.from].Position
+	}
+
+	if path.waiting > 0 {
+		path.waiting -= dt
+		return path.Waypoints[path.from].Position
+	}
+
+	from := path.Waypoints[path.from].Position
+	to := path.Waypoints[path.to].Position
+	leg := to.Subtract(from)
+	duration := leg.Magnitude() / path.Speed
+
+	path.elapsed += dt
+	if path.elapsed >= duration {
+		// Got to the waypoint, the rest of the step is lost
+		path.nextLeg()
+		return to
+	}
+
+	easing := path.Easing
+	if easing == nil {
+		easing = EaseLinear
+	}
+
+	/*
+		position = from + (to - from) * easing(elapsed / duration)
+	*/
+	travelled := easing(path.elapsed / duration)
+	return from.Add(leg.Multiply(travelled))
+}
+
+func (path *Path) nextLeg() {
+	last := len(path.Waypoints) - 1
+	arrived := path.to
+
+	path.elapsed = 0
+	path.waiting = path.Waypoints[arrived].Pause
+
+	direction := 1
+	if path.Mode == PATH_PING_PONG && path.to < path.from {
+		direction = -1
+	}
+	next := arrived + direction
+	if next < 0 || next > last {
+		switch path.Mode {
+		case PATH_LOOP:
+			next = (next + len(path.Waypoints)) % len(path.Waypoints)
+		case PATH_PING_PONG:
+			next = arrived - direction
+		default:
+			path.from = arrived
+			path.done = true
+			return
+		}
+	}
+
+	path.from = arrived
+	path.to = next
+}
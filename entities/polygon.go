@@ -3,59 +3,186 @@ package entities
 import (
 	"engine/renderer"
 	"engine/vector"
+	"fmt"
+	"math"
 )
 
 type Polygon struct {
 	Color         uint32
 	LocalVertices []vector.Vec2
 	WorldVertices []vector.Vec2
+
+	/*
+		One-way platform: the bodies only collide with it from the outside of
+		the edge at OneWayEdge and go through it from anywhere else. The edge
+		0 of a box is the top one, so for jump-through platforms setting
+		OneWay is enough (see collision.OneWayNormal).
+	*/
+	OneWay     bool
+	OneWayEdge int
 }
 
 // Passing the renderer is termporal for now
 func NewBox(color uint32, width float64, height float64) *Polygon {
+	return NewPolygon(color, []vector.Vec2{
+		{X: -width / 2.0, Y: -height / 2.0},
+		{X: width / 2.0, Y: -height / 2.0},
+		{X: width / 2.0, Y: height / 2.0},
+		{X: -width / 2.0, Y: height / 2.0},
+	})
+}
+
+/*
+Any convex polygon, the vertices are relative to the position of the body.
+They can go either way around, they are stored clockwise on the screen so
+the edge normals point out of the polygon (see Vec2.Normal). It panics if
+they don't make a convex polygon (see checkConvex).
+
+The vertices are moved so the centroid ends up at (0, 0): the body rotates
+around its center of mass, and that is where Position is. A triangle given
+as (0, 0), (30, 0), (0, 30) ends up as (-10, -10), (20, -10), (-10, 20).
+*/
+func NewPolygon(color uint32, vertices []vector.Vec2) *Polygon {
+	local := make([]vector.Vec2, len(vertices))
+	copy(local, vertices)
+
+	if signedArea(local) < 0 {
+		for i, j := 0, len(local)-1; i < j; i, j = i+1, j-1 {
+			local[i], local[j] = local[j], local[i]
+		}
+	}
+	checkConvex(local)
+
+	centroid := polygonCentroid(local)
+	for i := range local {
+		local[i] = local[i].Subtract(centroid)
+	}
+
+	world := make([]vector.Vec2, len(local))
+	copy(world, local)
+
 	return &Polygon{
-		Color: color,
-		LocalVertices: []vector.Vec2{
-			{X: -width / 2.0, Y: -height / 2.0},
-			{X: width / 2.0, Y: -height / 2.0},
-			{X: width / 2.0, Y: height / 2.0},
-			{X: -width / 2.0, Y: height / 2.0},
-		},
-		WorldVertices: []vector.Vec2{
-			{X: -width / 2.0, Y: -height / 2.0},
-			{X: width / 2.0, Y: -height / 2.0},
-			{X: width / 2.0, Y: height / 2.0},
-			{X: -width / 2.0, Y: height / 2.0},
-		},
+		Color:         color,
+		LocalVertices: local,
+		WorldVertices: world,
+	}
+}
+
+/*
+SAT and GJK only work with convex polygons, anything else would collide in
+weird ways so it is better to find out right away. The vertices already go
+clockwise, so every corner has to turn that way:
+
+	(vi+1 - vi) X (vi+2 - vi+1) >= 0
+
+and all of the turns have to add up to one lap, a star turns the same way
+at every corner but goes around twice.
+*/
+func checkConvex(vertices []vector.Vec2) {
+	count := len(vertices)
+	if count < 3 {
+		panic(fmt.Sprintf("entities: NewPolygon got %d vertices, it needs at least 3", count))
+	}
+
+	const tolerance = 1e-9
+	if signedArea(vertices) < tolerance {
+		panic("entities: NewPolygon got vertices without area, they are all on a line")
+	}
+
+	turn := 0.0
+	for i, vertex := range vertices {
+		edge := vertices[(i+1)%count].Subtract(vertex)
+		next := vertices[(i+2)%count].Subtract(vertices[(i+1)%count])
+		if edge.Magnitude() == 0 {
+			panic(fmt.Sprintf("entities: NewPolygon got the vertex %v twice in a row", vertex))
+		}
+
+		cross := edge.Cross(next)
+		if cross < -tolerance*edge.Magnitude()*next.Magnitude() {
+			panic("entities: NewPolygon got a concave polygon, use a compound body instead (see Compound)")
+		}
+		turn += math.Atan2(cross, edge.Dot(next))
+	}
+
+	if turn > 2*math.Pi+tolerance {
+		panic("entities: NewPolygon got a polygon that crosses itself")
 	}
 }
 
+/*
+Shoelace formula, the polygon is split in triangles between the origin and
+each edge:
+
+	area = 1/2 * sum(vi X vi+1)
+
+Positive when the vertices go clockwise on the screen (y points down).
+*/
+func signedArea(vertices []vector.Vec2) float64 {
+	area := 0.0
+	for i, vertex := range vertices {
+		next := vertices[(i+1)%len(vertices)]
+		area += vertex.Cross(next)
+	}
+	return area / 2
+}
+
+/*
+Average of the centroids of the same triangles, weighted by their area:
+
+	centroid = 1 / (6 * area) * sum((vi + vi+1) * (vi X vi+1))
+*/
+func polygonCentroid(vertices []vector.Vec2) vector.Vec2 {
+	area := signedArea(vertices)
+	if area == 0 {
+		return vector.Vec2{}
+	}
+
+	var centroid vector.Vec2
+	for i, vertex := range vertices {
+		next := vertices[(i+1)%len(vertices)]
+		sum := vertex.Add(next)
+		centroid = centroid.Add(sum.Multiply(vertex.Cross(next)))
+	}
+	return centroid.Multiply(1 / (6 * area))
+}
+
+func (polygon *Polygon) Area() float64 {
+	return math.Abs(signedArea(polygon.LocalVertices))
+}
+
+// Size of the bounding box of the polygon before rotating it (textures are drawn this big).
 func (polygon *Polygon) GetHeight() float64 {
-	vec := polygon.LocalVertices[1].Subtract(polygon.LocalVertices[0])
-	return vec.Magnitude()
+	aabb := AABBFromVertices(polygon.LocalVertices)
+	return aabb.Max.Y - aabb.Min.Y
 }
 
 func (polygon *Polygon) GetWidth() float64 {
-	vec := polygon.LocalVertices[3].Subtract(polygon.LocalVertices[0])
-	return vec.Magnitude()
+	aabb := AABBFromVertices(polygon.LocalVertices)
+	return aabb.Max.X - aabb.Min.X
 }
 
 /*
-TODO: How do we extend this interface for shapes with different amount of vertices.
-How do calculate the momentOfInertia for a shape do not know?
+Per unit of mass and around the centroid, like the rest of the shapes. Each
+triangle between the origin and an edge adds
+
+	I = (vi X vi+1) / 12 * (vi . vi + vi . vi+1 + vi+1 . vi+1)
+
+and the total is divided by the area. For a box it ends up being the usual
+(width^2 + height^2) / 12.
 */
 func (polygon *Polygon) MomentOfInertia() float64 {
-	if len(polygon.WorldVertices) == 4 {
-		v10 := polygon.LocalVertices[1].Subtract(polygon.LocalVertices[0])
-		v30 := polygon.LocalVertices[3].Subtract(polygon.LocalVertices[0])
-
-		width := v10.Magnitude()
-		height := v30.Magnitude()
+	vertices := polygon.LocalVertices
+	area := signedArea(vertices)
+	if area == 0 {
+		return 0
+	}
 
-		return 0.083333 * ((width * width) + (height * height))
-	} else {
-		return 5000
+	inertia := 0.0
+	for i, vertex := range vertices {
+		next := vertices[(i+1)%len(vertices)]
+		inertia += vertex.Cross(next) * (vertex.Dot(vertex) + vertex.Dot(next) + next.Dot(next))
 	}
+	return inertia / 12 / area
 }
 
 func (polygon *Polygon) EdgeAt(idx int) vector.Vec2 {
@@ -81,6 +208,22 @@ func (polygon *Polygon) UpdateVertices(position vector.Vec2, rotation float64) {
 	}
 }
 
+func (polygon *Polygon) GetAABB() AABB {
+	return AABBFromVertices(polygon.WorldVertices)
+}
+
+func (polygon *Polygon) Support(direction vector.Vec2) vector.Vec2 {
+	support := polygon.WorldVertices[0]
+	best := direction.Dot(support)
+	for _, vertex := range polygon.WorldVertices[1:] {
+		if projection := direction.Dot(vertex); projection > best {
+			best = projection
+			support = vertex
+		}
+	}
+	return support
+}
+
 func (polygon *Polygon) MarkDebug() {
 	polygon.Color = renderer.DEBUG
 }
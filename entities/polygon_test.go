@@ -0,0 +1,52 @@
+package entities
+
+import (
+	"engine/renderer"
+	"engine/vector"
+	"testing"
+)
+
+func TestNewPolygonInvalid(t *testing.T) {
+	cases := []struct {
+		name     string
+		vertices []vector.Vec2
+	}{
+		{"two vertices", []vector.Vec2{{X: 0, Y: 0}, {X: 10, Y: 0}}},
+		{"on a line", []vector.Vec2{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 20, Y: 0}}},
+		{"repeated vertex", []vector.Vec2{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 0}, {X: 0, Y: 10}}},
+		{"concave", []vector.Vec2{{X: 0, Y: 0}, {X: 20, Y: 0}, {X: 10, Y: 5}, {X: 20, Y: 20}, {X: 0, Y: 20}}},
+		{"star", []vector.Vec2{{X: 0, Y: -10}, {X: 6, Y: 8}, {X: -9.5, Y: -3}, {X: 9.5, Y: -3}, {X: -6, Y: 8}}},
+	}
+
+	for _, c := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: NewPolygon didn't panic", c.name)
+				}
+			}()
+			NewPolygon(renderer.WHITE, c.vertices)
+		}()
+	}
+}
+
+// Both windings end up clockwise on the screen, the edge normals point out of the polygon.
+func TestNewPolygonWinding(t *testing.T) {
+	clockwise := []vector.Vec2{{X: -10, Y: -10}, {X: 10, Y: -10}, {X: 10, Y: 10}, {X: -10, Y: 10}}
+	counterClockwise := []vector.Vec2{{X: -10, Y: 10}, {X: 10, Y: 10}, {X: 10, Y: -10}, {X: -10, Y: -10}}
+
+	for name, vertices := range map[string][]vector.Vec2{"clockwise": clockwise, "counter clockwise": counterClockwise} {
+		polygon := NewPolygon(renderer.WHITE, vertices)
+		if area := signedArea(polygon.LocalVertices); area != 400 {
+			t.Errorf("%s: signed area %.1f, want 400", name, area)
+		}
+
+		for i, vertex := range polygon.WorldVertices {
+			edge := polygon.EdgeAt(i)
+			normal := edge.Normal()
+			if normal.Dot(vertex) <= 0 {
+				t.Errorf("%s: normal %v of the edge %d points inside", name, normal, i)
+			}
+		}
+	}
+}
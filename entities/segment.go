@@ -0,0 +1,102 @@
+package entities
+
+import (
+	"engine/renderer"
+	"engine/vector"
+	"math"
+)
+
+/*
+Line between A and B without thickness, for static terrain. It collides on
+both sides.
+
+When the segment is part of a chain the ends know the vertices next to them
+(ghost vertices). A body sliding over the joint of two segments could hit
+the end of the next one and catch on it, the ghosts tell the narrowphase
+where the terrain goes so it ignores those contacts (see Chain).
+*/
+type Segment struct {
+	Color  uint32
+	LocalA vector.Vec2
+	LocalB vector.Vec2
+	A      vector.Vec2 // world positions, refreshed by UpdateVertices
+	B      vector.Vec2
+
+	// Vertex before A and after B, only if HasGhostA / HasGhostB
+	LocalGhostA vector.Vec2
+	LocalGhostB vector.Vec2
+	GhostA      vector.Vec2
+	GhostB      vector.Vec2
+	HasGhostA   bool
+	HasGhostB   bool
+}
+
+/*
+The ends are relative to the position of the body and they are not
+recentered, terrain is easier to build in the coordinates of the level.
+*/
+func NewSegment(color uint32, a vector.Vec2, b vector.Vec2) *Segment {
+	return &Segment{
+		Color:  color,
+		LocalA: a,
+		LocalB: b,
+		A:      a,
+		B:      b,
+	}
+}
+
+// No area, a segment body gets its mass from SetMass (or is static).
+func (segment *Segment) Area() float64 {
+	return 0
+}
+
+// Thin rod, length^2 / 12 around its middle.
+func (segment *Segment) MomentOfInertia() float64 {
+	ab := segment.LocalB.Subtract(segment.LocalA)
+	sum := segment.LocalA.Add(segment.LocalB)
+	middle := sum.Multiply(0.5)
+	return ab.Dot(ab)/12 + middle.Dot(middle)
+}
+
+func (segment *Segment) GetHeight() float64 {
+	return math.Abs(segment.LocalB.Y - segment.LocalA.Y)
+}
+
+func (segment *Segment) GetWidth() float64 {
+	return math.Abs(segment.LocalB.X - segment.LocalA.X)
+}
+
+func (segment *Segment) Draw(body *Body, rendr *renderer.Renderer) {
+	rendr.DrawLine(segment.A, segment.B, segment.Color)
+}
+
+func (segment *Segment) UpdateVertices(position vector.Vec2, rotation float64) {
+	segment.A = toWorld(segment.LocalA, position, rotation)
+	segment.B = toWorld(segment.LocalB, position, rotation)
+	segment.GhostA = toWorld(segment.LocalGhostA, position, rotation)
+	segment.GhostB = toWorld(segment.LocalGhostB, position, rotation)
+}
+
+func toWorld(local vector.Vec2, position vector.Vec2, rotation float64) vector.Vec2 {
+	world := local.Rotate(rotation)
+	return world.Add(position)
+}
+
+func (segment *Segment) GetAABB() AABB {
+	return AABBFromVertices([]vector.Vec2{segment.A, segment.B})
+}
+
+func (segment *Segment) Support(direction vector.Vec2) vector.Vec2 {
+	if direction.Dot(segment.B) > direction.Dot(segment.A) {
+		return segment.B
+	}
+	return segment.A
+}
+
+func (segment *Segment) MarkDebug() {
+	segment.Color = renderer.DEBUG
+}
+
+func (segment *Segment) UnMarkDebug() {
+	segment.Color = renderer.WHITE
+}
@@ -7,10 +7,14 @@ import (
 
 type Shape interface {
 	MomentOfInertia() float64
+	Area() float64 // pix^2, used to get the mass from the density
 	Draw(body *Body, renderer *renderer.Renderer)
 	MarkDebug()
 	UnMarkDebug()
 	GetHeight() float64
 	GetWidth() float64
 	UpdateVertices(position vector.Vec2, rotation float64)
+	GetAABB() AABB
+	// Farthest point of the shape along direction, in world coordinates (see collision/gjk.go)
+	Support(direction vector.Vec2) vector.Vec2
 }
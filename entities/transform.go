@@ -0,0 +1,9 @@
+package entities
+
+import "engine/vector"
+
+// Where a shape is placed in the world, for shapes that are not attached to a body.
+type Transform struct {
+	Position vector.Vec2
+	Rotation float64
+}
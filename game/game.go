@@ -1,6 +1,7 @@
 package game
 
 import (
+	"engine/collision"
 	"engine/constants"
 	"engine/entities"
 	"engine/renderer"
@@ -18,24 +19,27 @@ type Game struct {
 }
 
 func NewGame(name string, width int32, height int32) Game {
-	game := Game{Running: true}
+	game := Game{
+		Running: true,
+		World:   NewWorld(collision.NewDynamicTree(constants.AABB_MARGIN)),
+	}
 	rendr := renderer.NewRenderer(name, width, height)
 	game.Renderer = rendr
 	game.TimeToPreviousFrame = sdl.GetTicks64()
 
 	bottom := entities.NewBoxBody(
-		renderer.WHITE, float64(width-20), 50, 2, vector.Vec2{X: float64(width / 2), Y: float64(height - 20)}, 0, true,
+		renderer.WHITE, float64(width-20), 50, constants.DEFAULT_DENSITY, vector.Vec2{X: float64(width / 2), Y: float64(height - 20)}, 0, entities.BODY_STATIC,
 	)
 	left := entities.NewBoxBody(
-		renderer.WHITE, 50, float64(height-20), 2, vector.Vec2{X: 20, Y: float64(height / 2)}, 0, true,
+		renderer.WHITE, 50, float64(height-20), constants.DEFAULT_DENSITY, vector.Vec2{X: 20, Y: float64(height / 2)}, 0, entities.BODY_STATIC,
 	)
 
 	right := entities.NewBoxBody(
-		renderer.WHITE, 50, float64(height-20), 2, vector.Vec2{X: float64(width - 20), Y: float64(height / 2)}, 0, true,
+		renderer.WHITE, 50, float64(height-20), constants.DEFAULT_DENSITY, vector.Vec2{X: float64(width - 20), Y: float64(height / 2)}, 0, entities.BODY_STATIC,
 	)
 
 	bigBox := entities.NewBoxBody(
-		renderer.WHITE, 150, 150, 2, vector.Vec2{X: float64(width / 2), Y: float64(height / 2)}, 0, true,
+		renderer.WHITE, 150, 150, constants.DEFAULT_DENSITY, vector.Vec2{X: float64(width / 2), Y: float64(height / 2)}, 0, entities.BODY_STATIC,
 	)
 	bigBox.Name = "bigBox"
 	bigBox.Rotation = 1.4
@@ -66,8 +70,9 @@ func (game *Game) Input() {
 				vector.Vec2{X: float64(x), Y: float64(y)},
 				25,
 				renderer.WHITE,
-				2,
+				constants.DEFAULT_DENSITY,
 			)
+			circle.Bullet = true
 			circle.AttachTexture("./assets/bowlingball.png", &game.Renderer)
 			game.World.AddBody(&circle)
 		case renderer.MOUSE_BUTTON_RIGHT_UP:
@@ -75,15 +80,14 @@ func (game *Game) Input() {
 			polygonShape := entities.NewBox(renderer.WHITE, 50, 50)
 			polygon := entities.Body{
 				Position: vector.Vec2{X: float64(x), Y: float64(y)},
-				Mass:     2.0,
-				InvMass:  float64(1 / 2.0),
 				Shape:    polygonShape,
 				Rotation: 0.7,
-				Static:   false,
 				E:        0.1,
-				F:        0.7,
+				Fs:       0.7,
+				Fd:       0.5,
 				Name:     "Polygon",
 			}
+			polygon.SetDensity(constants.DEFAULT_DENSITY)
 			polygon.AttachTexture("./assets/crate.png", &game.Renderer)
 			game.World.AddBody(&polygon)
 		}
@@ -111,12 +115,19 @@ func (game *Game) Update() {
 	game.TimeToPreviousFrame = sdl.GetTicks64()
 
 	game.World.Update(deltaTime)
-	game.World.HandleCollisions()
 }
 
 func (game *Game) Draw() {
 	game.Renderer.ClearScreen()
 
+	// Highlights the bodies under the mouse
+	if game.DebugMode {
+		x, y := game.Renderer.GetMouseCoordinates()
+		for _, body := range game.World.QueryPoint(vector.Vec2{X: x, Y: y}, entities.DefaultFilter()) {
+			body.Shape.MarkDebug()
+		}
+	}
+
 	for i := range game.World.Bodies {
 		body := game.World.Bodies[i]
 		if body.Texture == nil {
@@ -0,0 +1,34 @@
+package game
+
+import "engine/collision"
+
+/*
+Lets the game know what the bodies are touching. Set it on World.ContactListener,
+all the methods are called during World.Update:
+
+  - BeginContact: the pair starts overlapping (see Collision.UpdateTouching).
+  - EndContact: the pair stopped overlapping, it gets the last collision
+    where they did.
+  - PreSolve: every step the pair has a collision, before it is solved. That
+    includes the steps right before they meet, when the bodies are closer
+    than CONTACT_MARGIN and the contacts are only speculative. It can set
+    Disabled or change the Restitution and the frictions of the collision
+    (for this step only). PassThrough is already decided for
+    one-way platforms, clearing it makes the platform solid for this pair.
+  - PostSolve: after the collision was solved, the NormalImpulse and
+    TangentImpulse of the contacts are what was applied (damage, sounds...).
+*/
+type ContactListener interface {
+	BeginContact(collision *collision.Collision)
+	EndContact(collision *collision.Collision)
+	PreSolve(collision *collision.Collision)
+	PostSolve(collision *collision.Collision)
+}
+
+// Can be embedded by listeners that only care about some of the events.
+type NoopContactListener struct{}
+
+func (listener *NoopContactListener) BeginContact(collision *collision.Collision) {}
+func (listener *NoopContactListener) EndContact(collision *collision.Collision)   {}
+func (listener *NoopContactListener) PreSolve(collision *collision.Collision)     {}
+func (listener *NoopContactListener) PostSolve(collision *collision.Collision)    {}
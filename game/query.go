@@ -0,0 +1,181 @@
+package game
+
+import (
+	"engine/collision"
+	"engine/entities"
+	"engine/vector"
+	"math"
+)
+
+/*
+Closest body hit by the ray that starts at origin and goes maxDistance pixels
+along direction. Only bodies that would collide with filter are hit, e.g. a
+filter with the group of the shooter skips the shooter. Like every query of
+the world it never hits sensors (see BodiesInside for those), and a 0
+direction hits nothing.
+*/
+func (world *World) RayCast(origin vector.Vec2, direction vector.Vec2, maxDistance float64, filter entities.Filter) (collision.RayCastHit, bool) {
+	var closest collision.RayCastHit
+	found := false
+
+	world.RayCastAll(origin, direction, maxDistance, filter, func(hit collision.RayCastHit) float64 {
+		closest = hit
+		found = true
+		// Only what is closer than this hit matters from now on
+		return hit.Fraction
+	})
+
+	return closest, found
+}
+
+/*
+Calls callback for the bodies hit by the ray, in no particular order. What
+the callback returns controls the rest of the cast:
+
+  - -1: ignore this hit and keep going.
+  - 0: stop.
+  - fraction: clip the ray, only the hits closer than fraction are reported.
+  - 1: keep going with the whole ray.
+*/
+func (world *World) RayCastAll(origin vector.Vec2, direction vector.Vec2, maxDistance float64, filter entities.Filter, callback func(hit collision.RayCastHit) float64) {
+	if direction.Magnitude() == 0 || maxDistance <= 0 {
+		return
+	}
+
+	ray := collision.Ray{Origin: origin, Direction: direction, MaxDistance: maxDistance}
+	unit := direction.Unit()
+	end := origin.Add(unit.Multiply(maxDistance))
+
+	// Only the bodies whose box overlaps the box of the ray can be hit
+	aabb := entities.AABB{
+		Min: vector.Vec2{X: math.Min(origin.X, end.X), Y: math.Min(origin.Y, end.Y)},
+		Max: vector.Vec2{X: math.Max(origin.X, end.X), Y: math.Max(origin.Y, end.Y)},
+	}
+
+	maxFraction := 1.0
+	world.updateBroadphase()
+	world.Broadphase.Query(aabb, func(body *entities.Body) bool {
+		if !queryable(body, filter) {
+			return true
+		}
+
+		hit, ok := collision.RayCast(body, ray)
+		if !ok || hit.Fraction > maxFraction {
+			return true
+		}
+
+		fraction := callback(hit)
+		if fraction == 0 {
+			return false
+		}
+		if fraction > 0 && fraction < maxFraction {
+			maxFraction = fraction
+		}
+		return true
+	})
+}
+
+// Bodies whose shape contains point, e.g. the ones under the mouse.
+func (world *World) QueryPoint(point vector.Vec2, filter entities.Filter) []*entities.Body {
+	aabb := entities.AABB{Min: point, Max: point}
+
+	return world.query(aabb, filter, func(body *entities.Body) bool {
+		return collision.TestPoint(body, point)
+	})
+}
+
+// Bodies whose bounding box overlaps aabb, the shapes themselves are not checked.
+func (world *World) QueryAABB(aabb entities.AABB, filter entities.Filter) []*entities.Body {
+	return world.query(aabb, filter, func(body *entities.Body) bool {
+		bodyAABB := body.GetAABB()
+		return bodyAABB.Overlaps(aabb)
+	})
+}
+
+/*
+Bodies that overlap shape placed at transform. The shape is moved there (see
+Shape.UpdateVertices), it shouldn't be the shape of a body of the world.
+*/
+func (world *World) QueryShape(shape entities.Shape, transform entities.Transform, filter entities.Filter) []*entities.Body {
+	shape.UpdateVertices(transform.Position, transform.Rotation)
+	probe := &entities.Body{
+		Position: transform.Position,
+		Rotation: transform.Rotation,
+		Shape:    shape,
+		Filter:   filter,
+	}
+
+	return world.query(shape.GetAABB(), filter, func(body *entities.Body) bool {
+		return collision.TestOverlap(probe, body)
+	})
+}
+
+// Bodies around aabb that pass the filter and test, in the order of World.Bodies. Sensors are skipped.
+func (world *World) query(aabb entities.AABB, filter entities.Filter, test func(body *entities.Body) bool) []*entities.Body {
+	found := map[*entities.Body]bool{}
+
+	world.updateBroadphase()
+	world.Broadphase.Query(aabb, func(body *entities.Body) bool {
+		if queryable(body, filter) && test(body) {
+			found[body] = true
+		}
+		return true
+	})
+
+	var bodies []*entities.Body
+	for _, body := range world.Bodies {
+		if found[body] {
+			bodies = append(bodies, body)
+		}
+	}
+	return bodies
+}
+
+/*
+Sweeps shape from start along translation and returns the first body it
+touches, e.g. to know where a character would stop before moving it. Only
+bodies that would collide with filter are hit, and sensors never are: a
+character sweeping its own shape passes a filter with its own negative group
+so it doesn't hit itself.
+
+The shape is left where it stopped (see Shape.UpdateVertices), it shouldn't be
+the shape of a body of the world.
+*/
+func (world *World) ShapeCast(shape entities.Shape, start entities.Transform, translation vector.Vec2, filter entities.Filter) (collision.ShapeCastHit, bool) {
+	// Everything the shape can touch lies inside of the swept box
+	shape.UpdateVertices(start.Position, start.Rotation)
+	startAABB := shape.GetAABB()
+	shape.UpdateVertices(start.Position.Add(translation), start.Rotation)
+	swept := startAABB.Union(shape.GetAABB())
+
+	var closest collision.ShapeCastHit
+	found := false
+
+	world.updateBroadphase()
+	world.Broadphase.Query(swept, func(body *entities.Body) bool {
+		if !queryable(body, filter) {
+			return true
+		}
+
+		hit, ok := collision.ShapeCast(shape, start, translation, body)
+		if ok && (!found || hit.Fraction < closest.Fraction) {
+			closest = hit
+			found = true
+		}
+		return true
+	})
+
+	// Leave the shape where it stopped
+	fraction := 1.0
+	if found {
+		fraction = closest.Fraction
+	}
+	shape.UpdateVertices(start.Position.Add(translation.Multiply(fraction)), start.Rotation)
+
+	return closest, found
+}
+
+// Bodies the queries can find: the ones that pass the filter, sensors never do.
+func queryable(body *entities.Body, filter entities.Filter) bool {
+	return !body.Sensor && filter.ShouldCollide(&body.Filter)
+}
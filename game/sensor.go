@@ -0,0 +1,68 @@
+package game
+
+import (
+	"engine/collision"
+	"engine/entities"
+)
+
+/*
+Gets the bodies going in and out of sensors (see Body.Sensor). Set it on
+World.SensorListener, the methods are called during World.Update.
+*/
+type SensorListener interface {
+	SensorEnter(sensor *entities.Body, body *entities.Body)
+	SensorExit(sensor *entities.Body, body *entities.Body)
+}
+
+type sensorPair struct {
+	sensor *entities.Body
+	body   *entities.Body
+}
+
+/*
+Sensors only report overlaps, the pair is never solved. Two sensors don't
+see each other, and static sensors don't see static bodies (neither of them
+ever moves), kinematic bodies going through them are reported.
+*/
+func (world *World) detectSensor(pair collision.Pair, inside map[sensorPair]bool, found []sensorPair) []sensorPair {
+	sensor, body := pair.BodyA, pair.BodyB
+	if !sensor.Sensor {
+		sensor, body = body, sensor
+	}
+	if body.Sensor || (sensor.IsStatic() && body.IsStatic()) || !collision.TestOverlap(sensor, body) {
+		return found
+	}
+
+	key := sensorPair{sensor: sensor, body: body}
+	if !world.sensorPairs[key] && world.SensorListener != nil {
+		world.SensorListener.SensorEnter(sensor, body)
+	}
+
+	inside[key] = true
+	return append(found, key)
+}
+
+// Fires the exits and keeps what is inside of the sensors for the next step.
+func (world *World) updateSensors(inside map[sensorPair]bool, found []sensorPair) {
+	if world.SensorListener != nil {
+		for _, previous := range world.sensorOverlaps {
+			if !inside[previous] {
+				world.SensorListener.SensorExit(previous.sensor, previous.body)
+			}
+		}
+	}
+
+	world.sensorPairs = inside
+	world.sensorOverlaps = found
+}
+
+// Bodies that are inside of sensor since the last step.
+func (world *World) BodiesInside(sensor *entities.Body) []*entities.Body {
+	var bodies []*entities.Body
+	for _, pair := range world.sensorOverlaps {
+		if pair.sensor == sensor {
+			bodies = append(bodies, pair.body)
+		}
+	}
+	return bodies
+}
@@ -2,18 +2,63 @@ package game
 
 import (
 	"engine/collision"
+	"engine/constants"
 	"engine/entities"
 	"engine/physics"
 	"engine/vector"
 )
 
 type World struct {
-	Bodies  []*entities.Body
-	Forces  []*vector.Vec2
-	Torques []*vector.Vec2
+	Bodies     []*entities.Body
+	Forces     []*vector.Vec2
+	Torques    []*vector.Vec2
+	Broadphase collision.Broadphase
+	Solver     *collision.Solver
+
+	// Get the contact and sensor events, nil if nobody is listening
+	ContactListener ContactListener
+	SensorListener  SensorListener
+
+	// Collisions of the last step, they are kept around to warm start the next one
+	contacts map[collision.PairKey]*collision.Collision
+	// Same collisions in the order they were found, plus the ones of every body
+	collisions   []*collision.Collision
+	bodyContacts map[*entities.Body][]*collision.Collision
+	// Bodies inside of the sensors, same thing for sensors
+	sensorPairs    map[sensorPair]bool
+	sensorOverlaps []sensorPair
+}
+
+func NewWorld(broadphase collision.Broadphase) World {
+	return World{
+		Broadphase: broadphase,
+		Solver:     collision.NewSolver(constants.VELOCITY_ITERATIONS),
+	}
+}
+
+/*
+Picks how the penetration between bodies is removed (see
+collision.PositionCorrection). beta is the % of the depth fixed per step and
+slop the pix of overlap that are never corrected.
+*/
+func (world *World) SetPositionCorrection(correction collision.PositionCorrection, beta float64, slop float64) {
+	if world.Solver == nil {
+		world.Solver = collision.NewSolver(constants.VELOCITY_ITERATIONS)
+	}
+
+	world.Solver.Correction = correction
+	world.Solver.Beta = beta
+	world.Solver.Slop = slop
 }
 
 func (world *World) AddBody(body *entities.Body) {
+	// Make sure the shape is in place before the first broadphase pass
+	body.Shape.UpdateVertices(body.Position, body.Rotation)
+
+	// Bodies built as literals have a Mass (or a Density) but no inverses yet
+	if body.IsDynamic() && body.InvMass == 0 && (body.Mass > 0 || body.Density > 0) {
+		body.ResetMassData()
+	}
 	world.Bodies = append(world.Bodies, body)
 }
 
@@ -38,17 +83,162 @@ func (world *World) Update(dt float64) {
 			body.SumForces = body.SumForces.Add(*torque)
 		}
 
-		body.Update(dt)
+		body.IntegrateForces(dt)
+
+		// Kinematic bodies on a path get the velocity before the solver needs it
+		if body.IsKinematic() {
+			body.FollowPath(dt)
+		}
 	}
+
+	world.HandleCollisions(dt)
+
+	for _, body := range world.Bodies {
+		if body.Bullet && body.IsDynamic() && !body.Sensor {
+			body.IntegrateVelocities(dt * world.timeOfImpact(body, dt))
+			continue
+		}
+		body.IntegrateVelocities(dt)
+	}
+
+	world.Solver.CorrectPositions()
 }
 
-func (world *World) HandleCollisions() {
-	// Check and resolve collisions for all bodies
-	for a := 0; a < len(world.Bodies)-1; a++ {
-		for b := a + 1; b < len(world.Bodies); b++ {
-			bodyA := &world.Bodies[a]
-			bodyB := &world.Bodies[b]
-			collision.Resolve(*bodyA, *bodyB)
+/*
+Fraction of the step a bullet can move before it hits the first static body.
+The bullet stops right before the wall and keeps its velocity, on the next
+step the wall is a regular contact.
+*/
+func (world *World) timeOfImpact(bullet *entities.Body, dt float64) float64 {
+	// Everything the bullet can reach this step lies inside of the swept box
+	start := bullet.GetAABB()
+	end := start
+	end.Min = end.Min.Add(bullet.Velocity.Multiply(dt))
+	end.Max = end.Max.Add(bullet.Velocity.Multiply(dt))
+	swept := start.Union(end)
+
+	toi := 1.0
+	for _, body := range world.Bodies {
+		if !body.IsStatic() || body.Sensor || !bullet.Filter.ShouldCollide(&body.Filter) {
+			continue
+		}
+
+		// One-way platforms only stop the bullets that come from the solid side
+		if oneWay, ok := collision.OneWayNormal(body); ok && bullet.Velocity.Dot(oneWay) >= 0 {
+			continue
 		}
+
+		aabb := body.GetAABB()
+		if !swept.Overlaps(aabb) {
+			continue
+		}
+
+		if t, hit := collision.TimeOfImpact(bullet, body, dt); hit && t < toi {
+			toi = t
+		}
+	}
+
+	return toi
+}
+
+func (world *World) HandleCollisions(dt float64) {
+	if world.Solver == nil {
+		world.Solver = collision.NewSolver(constants.VELOCITY_ITERATIONS)
 	}
+
+	// Only the pairs with overlapping bounding boxes go through the narrowphase
+	world.updateBroadphase()
+
+	var found []*collision.Collision
+	var collisions []*collision.Collision
+	contacts := make(map[collision.PairKey]*collision.Collision, len(world.contacts))
+	var overlaps []sensorPair
+	inside := make(map[sensorPair]bool, len(world.sensorPairs))
+	for _, pair := range world.Broadphase.Pairs() {
+		if !pair.BodyA.Filter.ShouldCollide(&pair.BodyB.Filter) {
+			continue
+		}
+
+		if pair.BodyA.Sensor || pair.BodyB.Sensor {
+			overlaps = world.detectSensor(pair, inside, overlaps)
+			continue
+		}
+
+		// Bodies with infinite mass can't push each other
+		if !pair.BodyA.IsDynamic() && !pair.BodyB.IsDynamic() {
+			continue
+		}
+
+		// One collision per pair of fixtures that touch
+		for _, c := range collision.Detect(pair.BodyA, pair.BodyB) {
+			previous, ok := world.contacts[c.Key()]
+			if ok {
+				c.WarmStart(previous)
+			}
+			c.UpdateOneWay(previous)
+			c.UpdateTouching(previous)
+
+			// Speculative contacts are solved but only overlapping pairs begin touching
+			wasTouching := ok && previous.Touching
+			if c.Touching && !wasTouching && world.ContactListener != nil {
+				world.ContactListener.BeginContact(c)
+			}
+
+			contacts[c.Key()] = c
+			found = append(found, c)
+
+			if world.ContactListener != nil {
+				world.ContactListener.PreSolve(c)
+			}
+
+			// Disabled collisions still count as touching, they just aren't solved
+			if !c.Disabled && !c.PassThrough {
+				collisions = append(collisions, c)
+			}
+		}
+	}
+
+	if world.ContactListener != nil {
+		for _, previous := range world.collisions {
+			if !previous.Touching {
+				continue
+			}
+			if current, ok := contacts[previous.Key()]; !ok || !current.Touching {
+				world.ContactListener.EndContact(previous)
+			}
+		}
+	}
+
+	world.updateSensors(inside, overlaps)
+
+	world.contacts = contacts
+	world.collisions = found
+	world.bodyContacts = make(map[*entities.Body][]*collision.Collision)
+	for _, c := range found {
+		if !c.Touching {
+			continue
+		}
+		world.bodyContacts[c.BodyA] = append(world.bodyContacts[c.BodyA], c)
+		world.bodyContacts[c.BodyB] = append(world.bodyContacts[c.BodyB], c)
+	}
+
+	world.Solver.Solve(collisions, dt)
+
+	if world.ContactListener != nil {
+		for _, c := range collisions {
+			world.ContactListener.PostSolve(c)
+		}
+	}
+}
+
+func (world *World) updateBroadphase() {
+	if world.Broadphase == nil {
+		world.Broadphase = collision.NewDynamicTree(constants.AABB_MARGIN)
+	}
+	world.Broadphase.Update(world.Bodies)
+}
+
+// Collisions the body is touching since the last step (see Collision.UpdateTouching), BodyA or BodyB is the body.
+func (world *World) ContactsOf(body *entities.Body) []*collision.Collision {
+	return world.bodyContacts[body]
 }
@@ -0,0 +1,33 @@
+package game
+
+import (
+	"engine/collision"
+	"engine/constants"
+	"engine/entities"
+	"engine/renderer"
+	"engine/vector"
+	"testing"
+)
+
+func TestLiteralBodyFalls(t *testing.T) {
+	world := NewWorld(collision.NewDynamicTree(constants.AABB_MARGIN))
+	body := &entities.Body{
+		Type:     entities.BODY_DYNAMIC,
+		Mass:     10,
+		Position: vector.Vec2{X: 0, Y: 0},
+		Shape:    entities.CircleShape(10, renderer.WHITE),
+		Filter:   entities.DefaultFilter(),
+	}
+	world.AddBody(body)
+
+	if body.InvMass != 0.1 {
+		t.Errorf("InvMass %.3f, want 0.1", body.InvMass)
+	}
+
+	for i := 0; i < 10; i++ {
+		world.Update(1 / float64(constants.FPS))
+	}
+	if body.Position.Y <= 0 || body.Velocity.Y <= 0 {
+		t.Errorf("body at %v with velocity %v, want it falling", body.Position, body.Velocity)
+	}
+}
@@ -208,6 +208,19 @@ func (renderer *Renderer) DrawCircle(x int32, y int32, radius int32, rotation fl
 	)
 }
 
+// Part of a circle, from start to end clockwise (radians, 0 points right).
+func (renderer *Renderer) DrawArc(x int32, y int32, radius int32, start float64, end float64, color uint32) {
+	gfx.ArcColor(
+		renderer.SDLRenderer,
+		x,
+		y,
+		radius,
+		int32(start*57.2958),
+		int32(end*57.2958),
+		fromHex(color),
+	)
+}
+
 func (renderer *Renderer) DrawFilledCircle(x int32, y int32, radius int32, color uint32) {
 	gfx.FilledCircleColor(renderer.SDLRenderer, x, y, radius, fromHex(color))
 }